@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"onefit/backend/models"
+	"onefit/backend/services"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalendarController struct {
+	db              *gorm.DB
+	calendarService *services.CalendarService
+}
+
+func NewCalendarController(db *gorm.DB) *CalendarController {
+	return &CalendarController{
+		db:              db,
+		calendarService: services.NewCalendarService(db),
+	}
+}
+
+// ScheduledEventRequest is the body of a scheduled event being created or replaced
+type ScheduledEventRequest struct {
+	EventType       string `json:"event_type" binding:"required"` // workout, fast or rest
+	Title           string `json:"title"`                         // Defaults from the template, target or type
+	TemplateID      *uint  `json:"template_id"`                   // Workout events
+	TargetHours     *int   `json:"target_hours"`                  // Fast events
+	StartDate       string `json:"start_date" binding:"required"` // YYYY-MM-DD
+	StartTime       string `json:"start_time"`                    // HH:MM; empty for all-day events
+	DurationMinutes int    `json:"duration_minutes"`
+	Recurrence      string `json:"recurrence"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE,FR
+	Notes           string `json:"notes"`
+}
+
+// toScheduledEventInput converts a request body to the service's input
+func toScheduledEventInput(req ScheduledEventRequest) (services.ScheduledEventInput, error) {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return services.ScheduledEventInput{}, fmt.Errorf("Invalid start_date format. Use YYYY-MM-DD")
+	}
+
+	return services.ScheduledEventInput{
+		EventType:       req.EventType,
+		Title:           req.Title,
+		TemplateID:      req.TemplateID,
+		TargetHours:     req.TargetHours,
+		StartDate:       startDate,
+		StartTime:       req.StartTime,
+		DurationMinutes: req.DurationMinutes,
+		Recurrence:      req.Recurrence,
+		Notes:           req.Notes,
+	}, nil
+}
+
+// getUserFromContext safely extracts user from gin context
+func (cc *CalendarController) getUserFromContext(c *gin.Context) (*models.User, error) {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil, fmt.Errorf("user not found in context")
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		return nil, fmt.Errorf("invalid user type in context")
+	}
+
+	return userModel, nil
+}
+
+// GetCalendar returns the user's planned workouts, fasts and rest days between two
+// dates, each marked planned, completed or missed
+func (cc *CalendarController) GetCalendar(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	// Workouts and fasts are matched to the day they started on in this time zone
+	loc := time.UTC
+	if timezone := c.Query("timezone"); timezone != "" {
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone. Use an IANA name such as Europe/London"})
+			return
+		}
+	}
+
+	occurrences, err := cc.calendarService.GetCalendar(userModel.ID, startDate, endDate, loc, time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCalendarRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"occurrences": occurrences,
+		"count":       len(occurrences),
+	})
+}
+
+// GetEvents returns the user's scheduled events
+func (cc *CalendarController) GetEvents(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := cc.calendarService.GetEvents(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scheduled events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// CreateEvent schedules a workout, fast or rest day
+func (cc *CalendarController) CreateEvent(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req ScheduledEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input, err := toScheduledEventInput(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := cc.calendarService.CreateEvent(userModel.ID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidEvent) || errors.Is(err, services.ErrInvalidRecurrence) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create scheduled event"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Event scheduled successfully",
+		"event":   event,
+	})
+}
+
+// UpdateEvent replaces a scheduled event
+func (cc *CalendarController) UpdateEvent(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var req ScheduledEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input, err := toScheduledEventInput(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := cc.calendarService.UpdateEvent(userModel.ID, uint(eventID), input)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled event not found"})
+		} else if errors.Is(err, services.ErrInvalidEvent) || errors.Is(err, services.ErrInvalidRecurrence) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduled event"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Scheduled event updated successfully",
+		"event":   event,
+	})
+}
+
+// DeleteEvent removes a scheduled event and all of its occurrences
+func (cc *CalendarController) DeleteEvent(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	err = cc.calendarService.DeleteEvent(userModel.ID, uint(eventID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled event not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete scheduled event"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled event deleted successfully"})
+}
+
+// GetFeed returns the user's iCalendar subscription URL, turning the feed on if needed
+func (cc *CalendarController) GetFeed(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := cc.calendarService.GetFeedToken(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feed_url": feedURL(c, token)})
+}
+
+// ResetFeed replaces the user's subscription URL; the old one stops working
+func (cc *CalendarController) ResetFeed(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := cc.calendarService.ResetFeedToken(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Calendar feed reset successfully",
+		"feed_url": feedURL(c, token),
+	})
+}
+
+// DisableFeed turns off the user's subscription URL
+func (cc *CalendarController) DisableFeed(c *gin.Context) {
+	userModel, err := cc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := cc.calendarService.DisableFeed(userModel.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed disabled successfully"})
+}
+
+// ServeFeed serves a calendar feed as an .ics file. It isn't authenticated: the
+// token in the URL is the secret.
+func (cc *CalendarController) ServeFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+
+	feed, err := cc.calendarService.GetFeed(token)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar feed"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(feed))
+}
+
+// feedURL builds the subscription URL of a feed token, from CALENDAR_BASE_URL when
+// set or else the host the request came in on
+func feedURL(c *gin.Context, token string) string {
+	baseURL := strings.TrimSuffix(os.Getenv("CALENDAR_BASE_URL"), "/")
+	if baseURL == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		baseURL = scheme + "://" + c.Request.Host
+	}
+	return baseURL + "/calendar/" + token + ".ics"
+}
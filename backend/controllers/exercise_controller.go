@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"onefit/backend/models"
@@ -14,12 +15,16 @@ import (
 type ExerciseController struct {
 	db              *gorm.DB
 	exerciseService *services.ExerciseService
+	mediaService    *services.MediaService
+	recordService   *services.PersonalRecordService
 }
 
 func NewExerciseController(db *gorm.DB) *ExerciseController {
 	return &ExerciseController{
 		db:              db,
 		exerciseService: services.NewExerciseService(db),
+		mediaService:    services.NewMediaService(db),
+		recordService:   services.NewPersonalRecordService(db),
 	}
 }
 
@@ -85,6 +90,105 @@ func (ec *ExerciseController) GetExercise(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"exercise": exercise})
 }
 
+// GetExerciseHistory returns the user's performance history for an exercise
+func (ec *ExerciseController) GetExerciseHistory(c *gin.Context) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	// Query parameters
+	startDate := c.Query("start_date") // YYYY-MM-DD
+	endDate := c.Query("end_date")     // YYYY-MM-DD
+	includeWarmups := c.DefaultQuery("include_warmups", "false") == "true"
+
+	history, err := ec.exerciseService.GetExerciseHistory(userModel.ID, uint(exerciseID), startDate, endDate, includeWarmups)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercise history"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+		"count":   len(history.Entries),
+	})
+}
+
+// GetExerciseRest returns the rest taken before the exercise's sets across recent workouts
+func (ec *ExerciseController) GetExerciseRest(c *gin.Context) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	sessions, _ := strconv.Atoi(c.DefaultQuery("sessions", "10"))
+	if sessions <= 0 {
+		sessions = 10
+	}
+
+	rest, err := ec.exerciseService.GetExerciseRestHistory(userModel.ID, uint(exerciseID), sessions)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rest history"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rest": rest})
+}
+
+// GetPersonalRecords returns the user's personal records for an exercise
+func (ec *ExerciseController) GetPersonalRecords(c *gin.Context) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	includeHistory := c.DefaultQuery("include_history", "false") == "true"
+
+	records, err := ec.recordService.GetPersonalRecords(userModel.ID, uint(exerciseID), includeHistory)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch personal records"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+		"count":   len(records),
+	})
+}
+
 // CreateExercise creates a new custom exercise
 func (ec *ExerciseController) CreateExercise(c *gin.Context) {
 	userModel, err := ec.getUserFromContext(c)
@@ -187,3 +291,170 @@ func (ec *ExerciseController) DeleteExercise(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Exercise deleted successfully"})
 }
+
+// MergeExercise merges a duplicate custom exercise into another exercise
+func (ec *ExerciseController) MergeExercise(c *gin.Context) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	type MergeExerciseInput struct {
+		TargetExerciseID uint `json:"target_exercise_id" binding:"required"`
+		DryRun           bool `json:"dry_run"` // Preview the merge without applying it
+	}
+
+	var input MergeExerciseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := ec.exerciseService.MergeCustomExercise(userModel.ID, uint(exerciseID), input.TargetExerciseID, input.DryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrMergeSameExercise) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found or not owned by user"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge exercises"})
+		}
+		return
+	}
+
+	message := "Exercises merged successfully"
+	if input.DryRun {
+		message = "Merge preview generated"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"merge":   preview,
+	})
+}
+
+// UploadExerciseImage attaches an image to a custom exercise (multipart field "file")
+func (ec *ExerciseController) UploadExerciseImage(c *gin.Context) {
+	ec.uploadExerciseMedia(c, services.MediaTypeImage)
+}
+
+// UploadExerciseVideo attaches a video clip to a custom exercise (multipart field "file")
+func (ec *ExerciseController) UploadExerciseVideo(c *gin.Context) {
+	ec.uploadExerciseMedia(c, services.MediaTypeVideo)
+}
+
+// uploadExerciseMedia handles the shared multipart upload flow for images and videos
+func (ec *ExerciseController) uploadExerciseMedia(c *gin.Context, mediaType string) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file must be uploaded in the 'file' form field"})
+		return
+	}
+
+	media, err := ec.mediaService.UploadExerciseMedia(userModel.ID, uint(exerciseID), mediaType, file)
+	if err != nil {
+		if errors.Is(err, services.ErrMediaTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrUnsupportedMedia) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrStorageUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found or not owned by user"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload media"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Media uploaded successfully",
+		"media":   media,
+	})
+}
+
+// GetExerciseMedia lists media attached to an exercise with signed download URLs
+func (ec *ExerciseController) GetExerciseMedia(c *gin.Context) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	media, err := ec.mediaService.GetExerciseMedia(userModel.ID, uint(exerciseID))
+	if err != nil {
+		if errors.Is(err, services.ErrStorageUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercise media"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"media": media,
+		"count": len(media),
+	})
+}
+
+// DeleteExerciseMedia removes a media attachment from a custom exercise
+func (ec *ExerciseController) DeleteExerciseMedia(c *gin.Context) {
+	userModel, err := ec.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	mediaID, err := strconv.ParseUint(c.Param("media_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+
+	err = ec.mediaService.DeleteExerciseMedia(userModel.ID, uint(exerciseID), uint(mediaID))
+	if err != nil {
+		if errors.Is(err, services.ErrStorageUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found or not owned by user"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
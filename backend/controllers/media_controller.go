@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"onefit/backend/storage"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MediaController struct{}
+
+func NewMediaController() *MediaController {
+	return &MediaController{}
+}
+
+// ServeMedia streams a file from local media storage after checking its URL signature
+func (mc *MediaController) ServeMedia(c *gin.Context) {
+	local := storage.GetLocalStorage()
+	if local == nil {
+		// Other backends hand out their own signed URLs
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !local.VerifySignature(key, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired media URL"})
+		return
+	}
+
+	path, err := local.FilePath(key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media key"})
+		return
+	}
+
+	c.File(path)
+}
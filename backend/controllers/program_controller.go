@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"onefit/backend/models"
+	"onefit/backend/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProgramController struct {
+	db             *gorm.DB
+	programService *services.ProgramService
+}
+
+func NewProgramController(db *gorm.DB) *ProgramController {
+	return &ProgramController{
+		db:             db,
+		programService: services.NewProgramService(db),
+	}
+}
+
+// getUserFromContext safely extracts user from gin context
+func (pc *ProgramController) getUserFromContext(c *gin.Context) (*models.User, error) {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil, fmt.Errorf("user not found in context")
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		return nil, fmt.Errorf("invalid user type in context")
+	}
+
+	return userModel, nil
+}
+
+// ProgramWeekInput is one week of a program; weeks are numbered in order
+type ProgramWeekInput struct {
+	LoadPercent *float64          `json:"load_percent"` // default 100
+	RepChange   int               `json:"rep_change"`
+	Notes       string            `json:"notes"`
+	Days        []ProgramDayInput `json:"days" binding:"dive"`
+}
+
+// ProgramDayInput schedules a template on a day of the week
+type ProgramDayInput struct {
+	DayNumber  int    `json:"day_number" binding:"required"` // 1-7
+	TemplateID uint   `json:"template_id" binding:"required"`
+	Name       string `json:"name"`
+}
+
+// toProgramWeeks converts week inputs for the program service
+func toProgramWeeks(inputs []ProgramWeekInput) []services.ProgramWeekInput {
+	weeks := make([]services.ProgramWeekInput, 0, len(inputs))
+	for _, input := range inputs {
+		days := make([]services.ProgramDayInput, 0, len(input.Days))
+		for _, day := range input.Days {
+			days = append(days, services.ProgramDayInput{
+				DayNumber:  day.DayNumber,
+				TemplateID: day.TemplateID,
+				Name:       day.Name,
+			})
+		}
+
+		weeks = append(weeks, services.ProgramWeekInput{
+			LoadPercent: input.LoadPercent,
+			RepChange:   input.RepChange,
+			Notes:       input.Notes,
+			Days:        days,
+		})
+	}
+	return weeks
+}
+
+// GetPrograms returns the user's programs
+func (pc *ProgramController) GetPrograms(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	programs, err := pc.programService.GetUserPrograms(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch programs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"programs": programs,
+		"count":    len(programs),
+	})
+}
+
+// GetProgram returns a program with its weeks and days
+func (pc *ProgramController) GetProgram(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	programID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	program, err := pc.programService.GetProgram(userModel.ID, uint(programID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch program"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"program": program})
+}
+
+// CreateProgram creates a multi-week program from templates
+func (pc *ProgramController) CreateProgram(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type CreateProgramInput struct {
+		Name        string             `json:"name" binding:"required"`
+		Description string             `json:"description"`
+		Weeks       []ProgramWeekInput `json:"weeks" binding:"required,dive"`
+	}
+
+	var input CreateProgramInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	program, err := pc.programService.CreateProgram(userModel.ID, input.Name, input.Description, toProgramWeeks(input.Weeks))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidProgram) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create program"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Program created successfully",
+		"program": program,
+	})
+}
+
+// UpdateProgram updates a program; weeks, when given, replace all of its weeks
+func (pc *ProgramController) UpdateProgram(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	programID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	type UpdateProgramInput struct {
+		Name        *string             `json:"name"`
+		Description *string             `json:"description"`
+		Weeks       *[]ProgramWeekInput `json:"weeks" binding:"omitempty,dive"`
+	}
+
+	var input UpdateProgramInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var weeks *[]services.ProgramWeekInput
+	if input.Weeks != nil {
+		programWeeks := toProgramWeeks(*input.Weeks)
+		weeks = &programWeeks
+	}
+
+	program, err := pc.programService.UpdateProgram(userModel.ID, uint(programID), input.Name, input.Description, weeks)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+		} else if errors.Is(err, services.ErrInvalidProgram) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrProgramInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update program"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Program updated successfully",
+		"program": program,
+	})
+}
+
+// DeleteProgram deletes a program and ends the user's enrolment in it
+func (pc *ProgramController) DeleteProgram(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	programID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	err = pc.programService.DeleteProgram(userModel.ID, uint(programID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete program"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Program deleted successfully"})
+}
+
+// Enroll starts the user on a program, replacing the one they were following
+func (pc *ProgramController) Enroll(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	programID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+		return
+	}
+
+	type EnrollInput struct {
+		StartDate string `json:"start_date"` // YYYY-MM-DD, defaults to today
+	}
+
+	var input EnrollInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	startDate := time.Now()
+	if input.StartDate != "" {
+		startDate, err = time.Parse("2006-01-02", input.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	enrollment, err := pc.programService.Enroll(userModel.ID, uint(programID), startDate)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+		} else if errors.Is(err, services.ErrInvalidProgram) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll in program"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Enrolled in program successfully",
+		"enrollment": enrollment,
+	})
+}
+
+// GetEnrollment returns the program the user is following with the days completed
+func (pc *ProgramController) GetEnrollment(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := pc.programService.GetEnrollment(userModel.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotEnrolled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch enrollment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enrollment": enrollment})
+}
+
+// LeaveProgram ends the user's enrolment
+func (pc *ProgramController) LeaveProgram(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = pc.programService.LeaveProgram(userModel.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotEnrolled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave program"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left program successfully"})
+}
+
+// GetTodaysWorkout returns what the user's program has planned for today, or for
+// the date given in the user's time zone
+func (pc *ProgramController) GetTodaysWorkout(c *gin.Context) {
+	userModel, err := pc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := time.Now()
+	if dateParam := c.Query("date"); dateParam != "" { // YYYY-MM-DD
+		date, err = time.Parse("2006-01-02", dateParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	today, err := pc.programService.GetTodaysWorkout(userModel.ID, date)
+	if err != nil {
+		if errors.Is(err, services.ErrNotEnrolled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch today's workout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"today": today})
+}
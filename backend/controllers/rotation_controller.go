@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"onefit/backend/models"
+	"onefit/backend/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RotationController struct {
+	db              *gorm.DB
+	rotationService *services.RotationService
+}
+
+func NewRotationController(db *gorm.DB) *RotationController {
+	return &RotationController{
+		db:              db,
+		rotationService: services.NewRotationService(db),
+	}
+}
+
+// getUserFromContext safely extracts user from gin context
+func (rc *RotationController) getUserFromContext(c *gin.Context) (*models.User, error) {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil, fmt.Errorf("user not found in context")
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		return nil, fmt.Errorf("invalid user type in context")
+	}
+
+	return userModel, nil
+}
+
+// GetRotations returns the user's template rotations
+func (rc *RotationController) GetRotations(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotations, err := rc.rotationService.GetUserRotations(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rotations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rotations": rotations,
+		"count":     len(rotations),
+	})
+}
+
+// GetRotation returns a rotation with its templates in order
+func (rc *RotationController) GetRotation(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rotation ID"})
+		return
+	}
+
+	rotation, err := rc.rotationService.GetRotation(userModel.ID, uint(rotationID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rotation not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rotation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rotation": rotation})
+}
+
+// CreateRotation creates a rotation cycling through templates in order
+func (rc *RotationController) CreateRotation(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type CreateRotationInput struct {
+		Name        string `json:"name" binding:"required"`
+		TemplateIDs []uint `json:"template_ids" binding:"required"`
+	}
+
+	var input CreateRotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotation, err := rc.rotationService.CreateRotation(userModel.ID, input.Name, input.TemplateIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRotation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rotation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Rotation created successfully",
+		"rotation": rotation,
+	})
+}
+
+// UpdateRotation renames a rotation or replaces its templates
+func (rc *RotationController) UpdateRotation(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rotation ID"})
+		return
+	}
+
+	type UpdateRotationInput struct {
+		Name        *string `json:"name"`
+		TemplateIDs *[]uint `json:"template_ids"` // Replaces all templates, in order
+	}
+
+	var input UpdateRotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotation, err := rc.rotationService.UpdateRotation(userModel.ID, uint(rotationID), input.Name, input.TemplateIDs)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rotation not found"})
+		} else if errors.Is(err, services.ErrInvalidRotation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rotation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Rotation updated successfully",
+		"rotation": rotation,
+	})
+}
+
+// DeleteRotation deletes a rotation
+func (rc *RotationController) DeleteRotation(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rotation ID"})
+		return
+	}
+
+	err = rc.rotationService.DeleteRotation(userModel.ID, uint(rotationID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rotation not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rotation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rotation deleted successfully"})
+}
+
+// GetNextTemplate returns the template due next in a rotation
+func (rc *RotationController) GetNextTemplate(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rotation ID"})
+		return
+	}
+
+	next, err := rc.rotationService.GetNextTemplate(userModel.ID, uint(rotationID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rotation not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch next template"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"next": next})
+}
+
+// SkipNext moves a rotation on past its next template
+func (rc *RotationController) SkipNext(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rotation ID"})
+		return
+	}
+
+	next, err := rc.rotationService.SkipNext(userModel.ID, uint(rotationID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rotation not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to skip template"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Template skipped successfully",
+		"next":    next,
+	})
+}
+
+// SwapNext makes another template of a rotation the next one
+func (rc *RotationController) SwapNext(c *gin.Context) {
+	userModel, err := rc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rotationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rotation ID"})
+		return
+	}
+
+	type SwapNextInput struct {
+		TemplateID uint `json:"template_id" binding:"required"`
+	}
+
+	var input SwapNextInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	next, err := rc.rotationService.SwapNext(userModel.ID, uint(rotationID), input.TemplateID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rotation not found"})
+		} else if errors.Is(err, services.ErrInvalidRotation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to swap template"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Template swapped successfully",
+		"next":    next,
+	})
+}
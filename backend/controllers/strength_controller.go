@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"onefit/backend/models"
+	"onefit/backend/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StrengthController struct {
+	db                 *gorm.DB
+	strengthService    *services.StrengthService
+	progressionService *services.ProgressionService
+}
+
+func NewStrengthController(db *gorm.DB) *StrengthController {
+	return &StrengthController{
+		db:                 db,
+		strengthService:    services.NewStrengthService(db),
+		progressionService: services.NewProgressionService(db),
+	}
+}
+
+// getUserFromContext safely extracts user from gin context
+func (sc *StrengthController) getUserFromContext(c *gin.Context) (*models.User, error) {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil, fmt.Errorf("user not found in context")
+	}
+
+	userModel, ok := user.(*models.User)
+	if !ok {
+		return nil, fmt.Errorf("invalid user type in context")
+	}
+
+	return userModel, nil
+}
+
+// GetFormulas lists the available one-rep-max formulas and the user's selection
+func (sc *StrengthController) GetFormulas(c *gin.Context) {
+	userModel, err := sc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	formula, err := sc.strengthService.GetUserFormula(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch formula preference"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"formulas":         services.OneRepMaxFormulas(),
+		"selected_formula": formula,
+	})
+}
+
+// UpdatePreferences changes the user's preferred one-rep-max formula
+func (sc *StrengthController) UpdatePreferences(c *gin.Context) {
+	userModel, err := sc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type UpdatePreferencesInput struct {
+		OneRepMaxFormula string `json:"one_rep_max_formula" binding:"required"`
+	}
+
+	var input UpdatePreferencesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = sc.strengthService.SetUserFormula(userModel.ID, input.OneRepMaxFormula)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownFormula) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update formula preference"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Preferences updated successfully",
+		"selected_formula": input.OneRepMaxFormula,
+	})
+}
+
+// CalculateOneRepMax estimates a 1RM from a weight and rep count with every formula
+func (sc *StrengthController) CalculateOneRepMax(c *gin.Context) {
+	type CalculateInput struct {
+		Weight float64 `json:"weight" binding:"required,gt=0"`
+		Reps   int     `json:"reps" binding:"required,gt=0"`
+		RPE    *int    `json:"rpe" binding:"omitempty,min=1,max=10"`
+	}
+
+	var input CalculateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	estimates := make(map[string]*float64)
+	for _, formula := range services.OneRepMaxFormulas() {
+		if estimate, ok := services.EstimateOneRepMax(formula.Key, input.Weight, input.Reps, input.RPE); ok {
+			estimates[formula.Key] = &estimate
+		} else {
+			estimates[formula.Key] = nil
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"estimates": estimates})
+}
+
+// GetWorkoutOneRepMax returns per-set and per-exercise estimates for a workout
+func (sc *StrengthController) GetWorkoutOneRepMax(c *gin.Context) {
+	userModel, err := sc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	result, err := sc.strengthService.GetWorkoutOneRepMax(userModel.ID, uint(workoutID), c.Query("formula"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownFormula) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate one-rep maxes"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"one_rep_max": result})
+}
+
+// GetExerciseTrend returns the estimated 1RM progression for an exercise
+func (sc *StrengthController) GetExerciseTrend(c *gin.Context) {
+	userModel, err := sc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exerciseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+		return
+	}
+
+	// Query parameters
+	formula := c.Query("formula")
+	startDate := c.Query("start_date") // YYYY-MM-DD
+	endDate := c.Query("end_date")     // YYYY-MM-DD
+
+	trend, err := sc.strengthService.GetOneRepMaxTrend(userModel.ID, uint(exerciseID), formula, startDate, endDate)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownFormula) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch one-rep-max trend"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trend": trend})
+}
+
+// GetProgressionIncrements returns the weight step used per equipment type when
+// suggesting progressive overload
+func (sc *StrengthController) GetProgressionIncrements(c *gin.Context) {
+	userModel, err := sc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	increments, err := sc.progressionService.GetIncrements(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progression increments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"increments": increments})
+}
+
+// UpdateProgressionIncrements overrides the weight step for one or more equipment types
+func (sc *StrengthController) UpdateProgressionIncrements(c *gin.Context) {
+	userModel, err := sc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type UpdateIncrementsInput struct {
+		Increments map[string]float64 `json:"increments" binding:"required"`
+	}
+
+	var input UpdateIncrementsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	increments, err := sc.progressionService.SetIncrements(userModel.ID, input.Increments)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidIncrement) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update progression increments"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Progression increments updated successfully",
+		"increments": increments,
+	})
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"onefit/backend/models"
 	"onefit/backend/services"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -48,9 +50,10 @@ func (tc *TemplateController) GetTemplates(c *gin.Context) {
 
 	// Query parameters
 	category := c.Query("category")
+	tag := c.Query("tag")
 	includePublic := c.DefaultQuery("include_public", "false") == "true"
 
-	templates, err := tc.templateService.GetUserTemplates(userModel.ID, category, includePublic)
+	templates, err := tc.templateService.GetUserTemplates(userModel.ID, category, tag, includePublic)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
 		return
@@ -62,6 +65,65 @@ func (tc *TemplateController) GetTemplates(c *gin.Context) {
 	})
 }
 
+// CreateTemplateFromWorkout saves a finished workout as a new template
+func (tc *TemplateController) CreateTemplateFromWorkout(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type CreateFromWorkoutInput struct {
+		WorkoutID      uint     `json:"workout_id" binding:"required"`
+		Name           string   `json:"name"` // defaults to the workout's name
+		Description    string   `json:"description"`
+		Category       string   `json:"category"`
+		IsPublic       bool     `json:"is_public"`
+		IncludeWarmups bool     `json:"include_warmups"`
+		PerSet         bool     `json:"per_set"`         // prescribe every set as logged
+		WeightRounding *float64 `json:"weight_rounding"` // kg; defaults to the equipment's progression increment
+		RestRounding   *int     `json:"rest_rounding"`   // seconds; defaults to 15
+	}
+
+	var input CreateFromWorkoutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if (input.WeightRounding != nil && *input.WeightRounding < 0) || (input.RestRounding != nil && *input.RestRounding < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rounding cannot be negative"})
+		return
+	}
+
+	options := services.TemplateFromWorkoutOptions{
+		IncludeWarmups: input.IncludeWarmups,
+		PerSet:         input.PerSet,
+		WeightRounding: input.WeightRounding,
+		RestRounding:   15,
+	}
+	if input.RestRounding != nil {
+		options.RestRounding = *input.RestRounding
+	}
+
+	template, err := tc.templateService.CreateTemplateFromWorkout(userModel.ID, input.WorkoutID, input.Name, input.Description, input.Category, input.IsPublic, options)
+	if err != nil {
+		if errors.Is(err, services.ErrTemplateNameTaken) || errors.Is(err, services.ErrWorkoutNotEnded) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template from workout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Template created from workout successfully",
+		"template": template,
+	})
+}
+
 // GetTemplate returns a single template with exercises
 func (tc *TemplateController) GetTemplate(c *gin.Context) {
 	userModel, err := tc.getUserFromContext(c)
@@ -98,10 +160,11 @@ func (tc *TemplateController) CreateTemplate(c *gin.Context) {
 	}
 
 	type CreateTemplateInput struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-		Category    string `json:"category"`
-		IsPublic    bool   `json:"is_public"`
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Category    string   `json:"category"`
+		IsPublic    bool     `json:"is_public"`
+		Tags        []string `json:"tags"`
 	}
 
 	var input CreateTemplateInput
@@ -110,9 +173,15 @@ func (tc *TemplateController) CreateTemplate(c *gin.Context) {
 		return
 	}
 
-	template, err := tc.templateService.CreateTemplate(userModel.ID, input.Name, input.Description, input.Category, input.IsPublic)
+	template, err := tc.templateService.CreateTemplate(userModel.ID, input.Name, input.Description, input.Category, input.IsPublic, input.Tags)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		if errors.Is(err, services.ErrTemplateNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		}
 		return
 	}
 
@@ -137,10 +206,11 @@ func (tc *TemplateController) UpdateTemplate(c *gin.Context) {
 	}
 
 	type UpdateTemplateInput struct {
-		Name        *string `json:"name"`
-		Description *string `json:"description"`
-		Category    *string `json:"category"`
-		IsPublic    *bool   `json:"is_public"`
+		Name        *string   `json:"name"`
+		Description *string   `json:"description"`
+		Category    *string   `json:"category"`
+		IsPublic    *bool     `json:"is_public"`
+		Tags        *[]string `json:"tags"` // Replaces all tags; [] removes them
 	}
 
 	var input UpdateTemplateInput
@@ -149,9 +219,13 @@ func (tc *TemplateController) UpdateTemplate(c *gin.Context) {
 		return
 	}
 
-	template, err := tc.templateService.UpdateTemplate(userModel.ID, uint(templateID), input.Name, input.Description, input.Category, input.IsPublic)
+	template, err := tc.templateService.UpdateTemplate(userModel.ID, uint(templateID), input.Name, input.Description, input.Category, input.IsPublic, input.Tags)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrTemplateNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found or not owned by user"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
@@ -192,6 +266,32 @@ func (tc *TemplateController) DeleteTemplate(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
 }
 
+// TemplateSetInput prescribes one set of a template exercise
+type TemplateSetInput struct {
+	TargetReps       string   `json:"target_reps"`
+	Weight           *float64 `json:"weight"`
+	PercentOneRepMax *float64 `json:"percent_one_rep_max"`
+	RPE              *float64 `json:"rpe"`
+	SetType          string   `json:"set_type"`
+	RestSeconds      *int     `json:"rest_seconds"`
+}
+
+// toTemplateSets converts set inputs into prescriptions in the given order
+func toTemplateSets(inputs []TemplateSetInput) []models.TemplateSet {
+	sets := make([]models.TemplateSet, 0, len(inputs))
+	for _, input := range inputs {
+		sets = append(sets, models.TemplateSet{
+			TargetReps:       input.TargetReps,
+			Weight:           input.Weight,
+			PercentOneRepMax: input.PercentOneRepMax,
+			RPE:              input.RPE,
+			SetType:          input.SetType,
+			RestSeconds:      input.RestSeconds,
+		})
+	}
+	return sets
+}
+
 // AddExerciseToTemplate adds an exercise to a template
 func (tc *TemplateController) AddExerciseToTemplate(c *gin.Context) {
 	userModel, err := tc.getUserFromContext(c)
@@ -207,12 +307,13 @@ func (tc *TemplateController) AddExerciseToTemplate(c *gin.Context) {
 	}
 
 	type AddExerciseInput struct {
-		ExerciseID   uint     `json:"exercise_id" binding:"required"`
-		OrderIndex   int      `json:"order_index"`
-		TargetSets   int      `json:"target_sets"`
-		TargetReps   string   `json:"target_reps"`
-		TargetWeight *float64 `json:"target_weight"`
-		RestSeconds  int      `json:"rest_seconds"`
+		ExerciseID   uint               `json:"exercise_id" binding:"required"`
+		OrderIndex   int                `json:"order_index"`
+		TargetSets   int                `json:"target_sets"`
+		TargetReps   string             `json:"target_reps"`
+		TargetWeight *float64           `json:"target_weight"`
+		RestSeconds  int                `json:"rest_seconds"`
+		Sets         []TemplateSetInput `json:"sets"`
 	}
 
 	var input AddExerciseInput
@@ -230,9 +331,12 @@ func (tc *TemplateController) AddExerciseToTemplate(c *gin.Context) {
 		input.TargetReps,
 		input.TargetWeight,
 		input.RestSeconds,
+		toTemplateSets(input.Sets),
 	)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrInvalidTarget) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Template or exercise not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add exercise to template"})
@@ -300,11 +404,12 @@ func (tc *TemplateController) UpdateTemplateExercise(c *gin.Context) {
 	}
 
 	type UpdateTemplateExerciseInput struct {
-		OrderIndex   *int     `json:"order_index"`
-		TargetSets   *int     `json:"target_sets"`
-		TargetReps   *string  `json:"target_reps"`
-		TargetWeight *float64 `json:"target_weight"`
-		RestSeconds  *int     `json:"rest_seconds"`
+		OrderIndex   *int                `json:"order_index"`
+		TargetSets   *int                `json:"target_sets"`
+		TargetReps   *string             `json:"target_reps"`
+		TargetWeight *float64            `json:"target_weight"`
+		RestSeconds  *int                `json:"rest_seconds"`
+		Sets         *[]TemplateSetInput `json:"sets"` // replaces all prescriptions; [] removes them
 	}
 
 	var input UpdateTemplateExerciseInput
@@ -313,6 +418,12 @@ func (tc *TemplateController) UpdateTemplateExercise(c *gin.Context) {
 		return
 	}
 
+	var sets *[]models.TemplateSet
+	if input.Sets != nil {
+		prescriptions := toTemplateSets(*input.Sets)
+		sets = &prescriptions
+	}
+
 	templateExercise, err := tc.templateService.UpdateTemplateExercise(
 		userModel.ID,
 		uint(templateID),
@@ -322,9 +433,12 @@ func (tc *TemplateController) UpdateTemplateExercise(c *gin.Context) {
 		input.TargetReps,
 		input.TargetWeight,
 		input.RestSeconds,
+		sets,
 	)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrInvalidTarget) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Template exercise not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template exercise"})
@@ -337,3 +451,435 @@ func (tc *TemplateController) UpdateTemplateExercise(c *gin.Context) {
 		"template_exercise": templateExercise,
 	})
 }
+
+// ReorderTemplateExercises sets the order of all exercises in a template at once
+func (tc *TemplateController) ReorderTemplateExercises(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	type ReorderInput struct {
+		ExerciseIDs []uint `json:"exercise_ids" binding:"required"`
+	}
+
+	var input ReorderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := tc.templateService.ReorderTemplateExercises(userModel.ID, uint(templateID), input.ExerciseIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder template exercises"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Template exercises reordered successfully",
+		"template": template,
+	})
+}
+
+// CreateTemplateGroup groups template exercises into a superset, giant set or circuit
+func (tc *TemplateController) CreateTemplateGroup(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	type CreateGroupInput struct {
+		GroupType                   string `json:"group_type" binding:"required"`
+		Name                        string `json:"name"`
+		Rounds                      int    `json:"rounds"`
+		RestBetweenExercisesSeconds int    `json:"rest_between_exercises_seconds"`
+		RestAfterRoundSeconds       int    `json:"rest_after_round_seconds"`
+		ExerciseIDs                 []uint `json:"exercise_ids" binding:"required"`
+	}
+
+	var input CreateGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := tc.templateService.CreateTemplateGroup(
+		userModel.ID,
+		uint(templateID),
+		input.GroupType,
+		input.Name,
+		input.Rounds,
+		input.RestBetweenExercisesSeconds,
+		input.RestAfterRoundSeconds,
+		input.ExerciseIDs,
+	)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGroup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template or template exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exercise group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Exercise group created successfully",
+		"group":   group,
+	})
+}
+
+// UpdateTemplateGroup changes a group's type, name, rounds or rest
+func (tc *TemplateController) UpdateTemplateGroup(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	groupID, err := strconv.ParseUint(c.Param("group_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	type UpdateGroupInput struct {
+		GroupType                   *string `json:"group_type"`
+		Name                        *string `json:"name"`
+		Rounds                      *int    `json:"rounds"`
+		RestBetweenExercisesSeconds *int    `json:"rest_between_exercises_seconds"`
+		RestAfterRoundSeconds       *int    `json:"rest_after_round_seconds"`
+	}
+
+	var input UpdateGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := tc.templateService.UpdateTemplateGroup(
+		userModel.ID,
+		uint(templateID),
+		uint(groupID),
+		input.GroupType,
+		input.Name,
+		input.Rounds,
+		input.RestBetweenExercisesSeconds,
+		input.RestAfterRoundSeconds,
+	)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGroup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise group not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exercise group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Exercise group updated successfully",
+		"group":   group,
+	})
+}
+
+// DeleteTemplateGroup ungroups the exercises of a group
+func (tc *TemplateController) DeleteTemplateGroup(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	groupID, err := strconv.ParseUint(c.Param("group_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	err = tc.templateService.DeleteTemplateGroup(userModel.ID, uint(templateID), uint(groupID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise group not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exercise group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exercise group deleted successfully"})
+}
+
+// DiscoverTemplates searches public templates with their author, ratings, saves and forks
+func (tc *TemplateController) DiscoverTemplates(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Query parameters
+	limit := c.DefaultQuery("limit", "20")
+	offset := c.DefaultQuery("offset", "0")
+
+	limitInt, _ := strconv.Atoi(limit)
+	offsetInt, _ := strconv.Atoi(offset)
+
+	filter := services.TemplateDiscoveryFilter{
+		Search:      strings.TrimSpace(c.Query("q")),
+		Category:    c.Query("category"),
+		MuscleGroup: strings.TrimSpace(c.Query("muscle")),
+		Equipment:   strings.TrimSpace(c.Query("equipment")),
+		Sort:        c.Query("sort"),
+	}
+
+	// Estimated length in minutes
+	if minDuration := c.Query("min_duration"); minDuration != "" {
+		filter.MinMinutes, err = strconv.Atoi(minDuration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_duration"})
+			return
+		}
+	}
+	if maxDuration := c.Query("max_duration"); maxDuration != "" {
+		filter.MaxMinutes, err = strconv.Atoi(maxDuration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_duration"})
+			return
+		}
+	}
+
+	templates, total, err := tc.templateService.DiscoverTemplates(userModel.ID, limitInt, offsetInt, filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDiscoveryFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"total":     total,
+		"limit":     limitInt,
+		"offset":    offsetInt,
+	})
+}
+
+// GetSavedTemplates returns the public templates the user saved
+func (tc *TemplateController) GetSavedTemplates(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templates, err := tc.templateService.GetSavedTemplates(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// DuplicateTemplate copies one of the user's own templates or a public one
+func (tc *TemplateController) DuplicateTemplate(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	type DuplicateTemplateInput struct {
+		Name string `json:"name"` // defaults to the original's name with " (Copy)"
+	}
+
+	// The body is optional
+	var input DuplicateTemplateInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	template, err := tc.templateService.DuplicateTemplate(userModel.ID, uint(templateID), input.Name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		} else if errors.Is(err, services.ErrTemplateNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to duplicate template"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Template duplicated successfully",
+		"template": template,
+	})
+}
+
+// RateTemplate rates someone else's public template from 1 to 5
+func (tc *TemplateController) RateTemplate(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	type RateTemplateInput struct {
+		Rating int `json:"rating" binding:"required"`
+	}
+
+	var input RateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := tc.templateService.RateTemplate(userModel.ID, uint(templateID), input.Rating)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Public template not found"})
+		} else if errors.Is(err, services.ErrInvalidRating) || errors.Is(err, services.ErrOwnTemplate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rate template"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Template rated successfully",
+		"template": template,
+	})
+}
+
+// RemoveRating withdraws the user's rating of a template
+func (tc *TemplateController) RemoveRating(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	if err := tc.templateService.RemoveRating(userModel.ID, uint(templateID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove rating"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rating removed successfully"})
+}
+
+// SaveTemplate adds someone else's public template to the user's saved templates
+func (tc *TemplateController) SaveTemplate(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	template, err := tc.templateService.SaveTemplate(userModel.ID, uint(templateID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Public template not found"})
+		} else if errors.Is(err, services.ErrOwnTemplate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save template"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Template saved successfully",
+		"template": template,
+	})
+}
+
+// UnsaveTemplate removes a template from the user's saved templates
+func (tc *TemplateController) UnsaveTemplate(c *gin.Context) {
+	userModel, err := tc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	if err := tc.templateService.UnsaveTemplate(userModel.ID, uint(templateID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsave template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template removed from saved templates"})
+}
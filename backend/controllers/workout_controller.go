@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"onefit/backend/models"
 	"onefit/backend/services"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,12 +17,14 @@ import (
 type WorkoutController struct {
 	db             *gorm.DB
 	workoutService *services.WorkoutService
+	tagService     *services.TagService
 }
 
 func NewWorkoutController(db *gorm.DB) *WorkoutController {
 	return &WorkoutController{
 		db:             db,
 		workoutService: services.NewWorkoutService(db),
+		tagService:     services.NewTagService(db),
 	}
 }
 
@@ -50,15 +54,59 @@ func (wc *WorkoutController) GetWorkouts(c *gin.Context) {
 	// Query parameters
 	limit := c.DefaultQuery("limit", "20")
 	offset := c.DefaultQuery("offset", "0")
-	startDate := c.Query("start_date") // YYYY-MM-DD
-	endDate := c.Query("end_date")     // YYYY-MM-DD
 
 	limitInt, _ := strconv.Atoi(limit)
 	offsetInt, _ := strconv.Atoi(offset)
 
-	workouts, total, err := wc.workoutService.GetUserWorkouts(userModel.ID, limitInt, offsetInt, startDate, endDate)
+	filter := services.WorkoutFilter{
+		StartDate: c.Query("start_date"), // YYYY-MM-DD
+		EndDate:   c.Query("end_date"),   // YYYY-MM-DD
+		Search:    strings.TrimSpace(c.Query("q")),
+	}
+
+	// Tags can be repeated or comma-separated; workouts must carry all of them
+	for _, tags := range c.QueryArray("tag") {
+		filter.Tags = append(filter.Tags, strings.Split(tags, ",")...)
+	}
+
+	if exerciseID := c.Query("exercise_id"); exerciseID != "" {
+		id, err := strconv.ParseUint(exerciseID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exercise ID"})
+			return
+		}
+		exerciseIDUint := uint(id)
+		filter.ExerciseID = &exerciseIDUint
+	}
+
+	// The summary view lists totals only; details come from GET /api/workouts/:id
+	if c.Query("view") == "summary" {
+		workouts, total, err := wc.workoutService.GetWorkoutHistory(userModel.ID, limitInt, offsetInt, filter)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTag) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"workouts": workouts,
+			"total":    total,
+			"limit":    limitInt,
+			"offset":   offsetInt,
+		})
+		return
+	}
+
+	workouts, total, err := wc.workoutService.GetUserWorkouts(userModel.ID, limitInt, offsetInt, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts"})
+		if errors.Is(err, services.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts"})
+		}
 		return
 	}
 
@@ -106,9 +154,12 @@ func (wc *WorkoutController) StartWorkout(c *gin.Context) {
 	}
 
 	type StartWorkoutInput struct {
-		Name       string `json:"name" binding:"required"`
-		TemplateID *uint  `json:"template_id"` // Optional - can start from template or freestyle
-		Notes      string `json:"notes"`
+		Name         string   `json:"name"`           // Required unless starting from a program day or rotation
+		TemplateID   *uint    `json:"template_id"`    // Optional - can start from template or freestyle
+		ProgramDayID *uint    `json:"program_day_id"` // Optional - a day of the program the user is enrolled in
+		RotationID   *uint    `json:"rotation_id"`    // Optional - start the rotation's next template
+		Notes        string   `json:"notes"`
+		Tags         []string `json:"tags"`
 	}
 
 	var input StartWorkoutInput
@@ -117,9 +168,62 @@ func (wc *WorkoutController) StartWorkout(c *gin.Context) {
 		return
 	}
 
-	workout, err := wc.workoutService.StartWorkout(userModel.ID, input.Name, input.TemplateID, input.Notes)
+	workout, err := wc.workoutService.StartWorkout(userModel.ID, input.Name, input.TemplateID, input.Notes, input.Tags, input.ProgramDayID, input.RotationID)
+	if err != nil {
+		if errors.Is(err, services.ErrActiveWorkoutExists) {
+			wc.respondActiveWorkoutExists(c, userModel.ID, err)
+		} else if errors.Is(err, services.ErrInvalidTag) || errors.Is(err, services.ErrWorkoutNameRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrProgramDayUnavailable) || errors.Is(err, services.ErrInvalidRotation) || errors.Is(err, services.ErrRotationUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start workout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Workout started successfully",
+		"workout": workout,
+	})
+}
+
+// RepeatWorkout starts a new workout cloned from a previous one, with last time's
+// sets as the plan
+func (wc *WorkoutController) RepeatWorkout(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	type RepeatWorkoutInput struct {
+		Name string `json:"name"` // Optional - defaults to the previous workout's name
+	}
+
+	var input RepeatWorkoutInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	workout, err := wc.workoutService.RepeatWorkout(userModel.ID, uint(workoutID), input.Name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start workout"})
+		if errors.Is(err, services.ErrActiveWorkoutExists) {
+			wc.respondActiveWorkoutExists(c, userModel.ID, err)
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to repeat workout"})
+		}
 		return
 	}
 
@@ -129,6 +233,95 @@ func (wc *WorkoutController) StartWorkout(c *gin.Context) {
 	})
 }
 
+// respondActiveWorkoutExists answers 409 with the ongoing workout so the client can
+// offer to resume it instead
+func (wc *WorkoutController) respondActiveWorkoutExists(c *gin.Context, userID uint, err error) {
+	response := gin.H{"error": err.Error()}
+	if active, activeErr := wc.workoutService.GetActiveWorkout(userID); activeErr == nil && active != nil {
+		response["active_workout_id"] = active.ID
+	}
+	c.JSON(http.StatusConflict, response)
+}
+
+// LogPastWorkout records a finished workout after the fact, with explicit times
+func (wc *WorkoutController) LogPastWorkout(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type PastSetInput struct {
+		SetType         string    `json:"set_type"`
+		Reps            *int      `json:"reps"`
+		Weight          *float64  `json:"weight"`
+		DurationSeconds *int      `json:"duration_seconds"`
+		DistanceMeters  *float64  `json:"distance_meters"`
+		RPE             *int      `json:"rpe"`
+		CompletedAt     time.Time `json:"completed_at" binding:"required"`
+	}
+
+	type PastExerciseInput struct {
+		ExerciseID uint           `json:"exercise_id" binding:"required"`
+		Notes      string         `json:"notes"`
+		Sets       []PastSetInput `json:"sets" binding:"dive"`
+	}
+
+	type LogPastWorkoutInput struct {
+		Name       string              `json:"name" binding:"required"`
+		TemplateID *uint               `json:"template_id"`
+		Notes      string              `json:"notes"`
+		StartedAt  time.Time           `json:"started_at" binding:"required"`
+		EndedAt    time.Time           `json:"ended_at" binding:"required"`
+		Exercises  []PastExerciseInput `json:"exercises" binding:"dive"`
+	}
+
+	var input LogPastWorkoutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exercises := make([]services.PastExercise, 0, len(input.Exercises))
+	for _, exercise := range input.Exercises {
+		sets := make([]services.PastSet, 0, len(exercise.Sets))
+		for _, set := range exercise.Sets {
+			sets = append(sets, services.PastSet{
+				SetType:         set.SetType,
+				Reps:            set.Reps,
+				Weight:          set.Weight,
+				DurationSeconds: set.DurationSeconds,
+				DistanceMeters:  set.DistanceMeters,
+				RPE:             set.RPE,
+				CompletedAt:     set.CompletedAt,
+			})
+		}
+
+		exercises = append(exercises, services.PastExercise{
+			ExerciseID: exercise.ExerciseID,
+			Notes:      exercise.Notes,
+			Sets:       sets,
+		})
+	}
+
+	workout, err := wc.workoutService.LogPastWorkout(userModel.ID, input.Name, input.Notes, input.TemplateID, input.StartedAt, input.EndedAt, exercises)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTimestamp) || errors.Is(err, services.ErrInvalidSetType) || errors.Is(err, services.ErrMissingSetMetric) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template or exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log workout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Workout logged successfully",
+		"workout": workout,
+	})
+}
+
 // UpdateWorkout updates workout details or finishes the workout
 func (wc *WorkoutController) UpdateWorkout(c *gin.Context) {
 	userModel, err := wc.getUserFromContext(c)
@@ -148,6 +341,7 @@ func (wc *WorkoutController) UpdateWorkout(c *gin.Context) {
 		Notes    *string    `json:"notes"`
 		EndedAt  *time.Time `json:"ended_at"`  // Set to finish workout
 		IsActive *bool      `json:"is_active"` // Set to false to finish workout
+		Tags     *[]string  `json:"tags"`      // Replaces all tags; [] removes them
 	}
 
 	var input UpdateWorkoutInput
@@ -156,9 +350,11 @@ func (wc *WorkoutController) UpdateWorkout(c *gin.Context) {
 		return
 	}
 
-	workout, err := wc.workoutService.UpdateWorkout(userModel.ID, uint(workoutID), input.Name, input.Notes, input.EndedAt, input.IsActive)
+	workout, err := wc.workoutService.UpdateWorkout(userModel.ID, uint(workoutID), input.Name, input.Notes, input.EndedAt, input.IsActive, input.Tags)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found or not owned by user"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout"})
@@ -172,6 +368,70 @@ func (wc *WorkoutController) UpdateWorkout(c *gin.Context) {
 	})
 }
 
+// PauseWorkout pauses an ongoing workout
+func (wc *WorkoutController) PauseWorkout(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	workout, err := wc.workoutService.PauseWorkout(userModel.ID, uint(workoutID))
+	if err != nil {
+		if errors.Is(err, services.ErrWorkoutEnded) || errors.Is(err, services.ErrWorkoutPaused) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause workout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workout paused successfully",
+		"workout": workout,
+	})
+}
+
+// ResumeWorkout resumes a paused workout
+func (wc *WorkoutController) ResumeWorkout(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	workout, err := wc.workoutService.ResumeWorkout(userModel.ID, uint(workoutID))
+	if err != nil {
+		if errors.Is(err, services.ErrWorkoutEnded) || errors.Is(err, services.ErrWorkoutNotPaused) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume workout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workout resumed successfully",
+		"workout": workout,
+	})
+}
+
 // DeleteWorkout deletes a workout session
 func (wc *WorkoutController) DeleteWorkout(c *gin.Context) {
 	userModel, err := wc.getUserFromContext(c)
@@ -310,11 +570,15 @@ func (wc *WorkoutController) LogSet(c *gin.Context) {
 	}
 
 	type LogSetInput struct {
-		Reps            *int     `json:"reps"`
-		Weight          *float64 `json:"weight"`
-		DurationSeconds *int     `json:"duration_seconds"`
-		DistanceMeters  *float64 `json:"distance_meters"`
-		RPE             *int     `json:"rpe"` // Rate of Perceived Exertion (1-10)
+		PlannedSetID    *uint      `json:"planned_set_id"` // optional - defaults to the next open planned set
+		SetType         string     `json:"set_type"`       // warmup, working, drop, failure or rest_pause
+		ParentSetID     *uint      `json:"parent_set_id"`  // drop sets only - defaults to the previous set
+		Reps            *int       `json:"reps"`
+		Weight          *float64   `json:"weight"`
+		DurationSeconds *int       `json:"duration_seconds"`
+		DistanceMeters  *float64   `json:"distance_meters"`
+		RPE             *int       `json:"rpe"`          // Rate of Perceived Exertion (1-10)
+		CompletedAt     *time.Time `json:"completed_at"` // optional - defaults to now
 	}
 
 	var input LogSetInput
@@ -323,9 +587,13 @@ func (wc *WorkoutController) LogSet(c *gin.Context) {
 		return
 	}
 
-	exerciseSet, err := wc.workoutService.LogSet(userModel.ID, uint(workoutID), uint(sessionExerciseID), input.Reps, input.Weight, input.DurationSeconds, input.DistanceMeters, input.RPE)
+	exerciseSet, err := wc.workoutService.LogSet(userModel.ID, uint(workoutID), uint(sessionExerciseID), input.PlannedSetID, input.SetType, input.ParentSetID, input.Reps, input.Weight, input.DurationSeconds, input.DistanceMeters, input.RPE, input.CompletedAt)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrPlannedSetUnavailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrInvalidSetType) || errors.Is(err, services.ErrInvalidTimestamp) || errors.Is(err, services.ErrMissingSetMetric) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Session exercise not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log set"})
@@ -334,8 +602,10 @@ func (wc *WorkoutController) LogSet(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Set logged successfully",
-		"set":     exerciseSet,
+		"message":            "Set logged successfully",
+		"set":                exerciseSet,
+		"is_personal_record": len(exerciseSet.PersonalRecords) > 0,
+		"rest_timer_ends_at": exerciseSet.RestTimerEndsAt,
 	})
 }
 
@@ -360,11 +630,14 @@ func (wc *WorkoutController) UpdateSet(c *gin.Context) {
 	}
 
 	type UpdateSetInput struct {
-		Reps            *int     `json:"reps"`
-		Weight          *float64 `json:"weight"`
-		DurationSeconds *int     `json:"duration_seconds"`
-		DistanceMeters  *float64 `json:"distance_meters"`
-		RPE             *int     `json:"rpe"`
+		SetType         *string    `json:"set_type"`
+		ParentSetID     *uint      `json:"parent_set_id"`
+		Reps            *int       `json:"reps"`
+		Weight          *float64   `json:"weight"`
+		DurationSeconds *int       `json:"duration_seconds"`
+		DistanceMeters  *float64   `json:"distance_meters"`
+		RPE             *int       `json:"rpe"`
+		CompletedAt     *time.Time `json:"completed_at"`
 	}
 
 	var input UpdateSetInput
@@ -373,9 +646,11 @@ func (wc *WorkoutController) UpdateSet(c *gin.Context) {
 		return
 	}
 
-	exerciseSet, err := wc.workoutService.UpdateSet(userModel.ID, uint(workoutID), uint(setID), input.Reps, input.Weight, input.DurationSeconds, input.DistanceMeters, input.RPE)
+	exerciseSet, err := wc.workoutService.UpdateSet(userModel.ID, uint(workoutID), uint(setID), input.SetType, input.ParentSetID, input.Reps, input.Weight, input.DurationSeconds, input.DistanceMeters, input.RPE, input.CompletedAt)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, services.ErrInvalidSetType) || errors.Is(err, services.ErrInvalidTimestamp) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Set not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update set"})
@@ -384,8 +659,9 @@ func (wc *WorkoutController) UpdateSet(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Set updated successfully",
-		"set":     exerciseSet,
+		"message":            "Set updated successfully",
+		"set":                exerciseSet,
+		"is_personal_record": len(exerciseSet.PersonalRecords) > 0,
 	})
 }
 
@@ -459,7 +735,9 @@ func (wc *WorkoutController) GetWorkoutStats(c *gin.Context) {
 		daysInt = 30
 	}
 
-	stats, err := wc.workoutService.GetWorkoutStats(userModel.ID, daysInt)
+	includeWarmups := c.DefaultQuery("include_warmups", "false") == "true"
+
+	stats, err := wc.workoutService.GetWorkoutStats(userModel.ID, daysInt, includeWarmups)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workout stats"})
 		return
@@ -468,6 +746,79 @@ func (wc *WorkoutController) GetWorkoutStats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"stats": stats})
 }
 
+// GetWorkoutRest returns the rest taken between sets compared with the prescribed rest
+func (wc *WorkoutController) GetWorkoutRest(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	rest, err := wc.workoutService.GetWorkoutRest(userModel.ID, uint(workoutID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rest statistics"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rest": rest})
+}
+
+// GetTags lists the user's tags with how many workouts and templates use each
+func (wc *WorkoutController) GetTags(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, err := wc.tagService.GetUserTags(userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// GetWorkoutSummary returns the completion summary of a finished workout
+func (wc *WorkoutController) GetWorkoutSummary(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	summary, err := wc.workoutService.GetWorkoutSummary(userModel.ID, uint(workoutID))
+	if err != nil {
+		if errors.Is(err, services.ErrWorkoutNotEnded) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workout summary"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
+
 // RemoveExerciseFromWorkout removes an exercise from a workout session
 func (wc *WorkoutController) RemoveExerciseFromWorkout(c *gin.Context) {
 	userModel, err := wc.getUserFromContext(c)
@@ -500,3 +851,134 @@ func (wc *WorkoutController) RemoveExerciseFromWorkout(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Exercise removed from workout successfully"})
 }
+
+// ReorderWorkoutExercises sets the order of all exercises in a workout at once
+func (wc *WorkoutController) ReorderWorkoutExercises(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	type ReorderInput struct {
+		ExerciseIDs []uint `json:"exercise_ids" binding:"required"` // session exercise IDs
+	}
+
+	var input ReorderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workout, err := wc.workoutService.ReorderWorkoutExercises(userModel.ID, uint(workoutID), input.ExerciseIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder workout exercises"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workout exercises reordered successfully",
+		"workout": workout,
+	})
+}
+
+// CreateWorkoutGroup groups workout exercises into a superset, giant set or circuit
+func (wc *WorkoutController) CreateWorkoutGroup(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	type CreateGroupInput struct {
+		GroupType                   string `json:"group_type" binding:"required"`
+		Name                        string `json:"name"`
+		Rounds                      int    `json:"rounds"`
+		RestBetweenExercisesSeconds int    `json:"rest_between_exercises_seconds"`
+		RestAfterRoundSeconds       int    `json:"rest_after_round_seconds"`
+		SessionExerciseIDs          []uint `json:"session_exercise_ids" binding:"required"`
+	}
+
+	var input CreateGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := wc.workoutService.CreateWorkoutGroup(
+		userModel.ID,
+		uint(workoutID),
+		input.GroupType,
+		input.Name,
+		input.Rounds,
+		input.RestBetweenExercisesSeconds,
+		input.RestAfterRoundSeconds,
+		input.SessionExerciseIDs,
+	)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGroup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout or session exercise not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exercise group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Exercise group created successfully",
+		"group":   group,
+	})
+}
+
+// DeleteWorkoutGroup ungroups the exercises of a group
+func (wc *WorkoutController) DeleteWorkoutGroup(c *gin.Context) {
+	userModel, err := wc.getUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workoutID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout ID"})
+		return
+	}
+
+	groupID, err := strconv.ParseUint(c.Param("group_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	err = wc.workoutService.DeleteWorkoutGroup(userModel.ID, uint(workoutID), uint(groupID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise group not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exercise group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exercise group deleted successfully"})
+}
@@ -2,7 +2,10 @@ module onefit/backend
 
 go 1.23.4
 
-require gorm.io/driver/sqlite v1.5.7
+require (
+	golang.org/x/image v0.24.0
+	gorm.io/driver/sqlite v1.5.7
+)
 
 require (
 	cel.dev/expr v0.24.0 // indirect
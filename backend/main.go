@@ -4,9 +4,12 @@ import (
 	"log"
 	"onefit/backend/models"
 	"onefit/backend/routes"
+	"onefit/backend/services"
+	"onefit/backend/storage"
 	"onefit/backend/utils"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -43,6 +46,16 @@ func main() {
 	// Setup Database
 	db := models.SetupDB()
 
+	// Close workouts left active side by side before only one was allowed, then
+	// enforce it
+	closed, err := services.NewWorkoutService(db).CloseDuplicateActiveWorkouts()
+	if err != nil {
+		log.Printf("Warning: Failed to close duplicate active workouts: %v", err)
+	} else if closed > 0 {
+		log.Printf("Auto-closed %d duplicate active workouts", closed)
+	}
+	models.CreateIndexes(db)
+
 	// Create test user only in development
 	if os.Getenv("GIN_MODE") != "release" {
 		// Clean up old test user without FirebaseUID
@@ -56,6 +69,26 @@ func main() {
 		log.Println("Firebase features will not be available")
 	}
 
+	// Initialize media storage
+	if err := storage.InitStorage(); err != nil {
+		log.Printf("Warning: Failed to initialize media storage: %v", err)
+		log.Println("Exercise media uploads will not be available")
+	}
+
+	// Auto-finish workouts left open; WORKOUT_IDLE_TIMEOUT=0 turns this off
+	idleTimeout := 6 * time.Hour // fallback
+	if value := os.Getenv("WORKOUT_IDLE_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Warning: Invalid WORKOUT_IDLE_TIMEOUT %q, using %s", value, idleTimeout)
+		} else {
+			idleTimeout = parsed
+		}
+	}
+	if idleTimeout > 0 {
+		services.StartIdleWorkoutSweeper(db, idleTimeout, min(idleTimeout, 15*time.Minute))
+	}
+
 	// Setup Routes
 	routes.SetupAuthRoutes(r, db)
 	routes.SetupFastingRoutes(r, db)
@@ -63,6 +96,11 @@ func main() {
 	routes.SetupExerciseRoutes(r, db)
 	routes.SetupWorkoutRoutes(r, db)
 	routes.SetupTemplateRoutes(r, db)
+	routes.SetupProgramRoutes(r, db)
+	routes.SetupRotationRoutes(r, db)
+	routes.SetupCalendarRoutes(r, db)
+	routes.SetupStrengthRoutes(r, db)
+	routes.SetupMediaRoutes(r)
 
 	// Basic Routes
 	r.GET("/", func(c *gin.Context) {
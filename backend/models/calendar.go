@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// ScheduledEvent plans a workout, fast or rest day on a date, optionally repeating.
+// Dates and times are the user's local ones, with no time zone attached.
+type ScheduledEvent struct {
+	Base
+	UserID          uint             `json:"user_id" gorm:"not null;index"`
+	EventType       string           `json:"event_type" gorm:"size:20;not null"`
+	Title           string           `json:"title"`
+	TemplateID      *uint            `json:"template_id" gorm:"index"`   // workout events
+	TargetHours     *int             `json:"target_hours"`               // fast events
+	StartDate       time.Time        `json:"start_date" gorm:"not null"` // first occurrence, stored as midnight UTC
+	StartTime       string           `json:"start_time" gorm:"size:5"`   // HH:MM; empty for all-day events
+	DurationMinutes int              `json:"duration_minutes"`           // length of timed events
+	Recurrence      string           `json:"recurrence"`                 // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE,FR; empty for one-off events
+	Notes           string           `json:"notes" gorm:"type:text"`
+	User            User             `json:"-" gorm:"foreignKey:UserID"`
+	Template        *WorkoutTemplate `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
+}
+
+// Event types
+const (
+	EventTypeWorkout = "workout"
+	EventTypeFast    = "fast"
+	EventTypeRest    = "rest"
+)
+
+// CalendarFeed holds the secret token in a user's iCalendar subscription URL
+type CalendarFeed struct {
+	Base
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex"`
+	Token  string `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	User   User   `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (ScheduledEvent) TableName() string {
+	return "scheduled_events"
+}
+
+func (CalendarFeed) TableName() string {
+	return "calendar_feeds"
+}
@@ -0,0 +1,55 @@
+package models
+
+// Exercise group types
+const (
+	GroupSuperset = "superset"  // two exercises back to back
+	GroupGiantSet = "giant_set" // three or more exercises back to back
+	GroupCircuit  = "circuit"   // any number of exercises, usually for time or conditioning
+)
+
+// GroupMinExercises returns how many exercises a group type needs, or 0 for an unknown type
+func GroupMinExercises(groupType string) int {
+	switch groupType {
+	case GroupSuperset, GroupCircuit:
+		return 2
+	case GroupGiantSet:
+		return 3
+	}
+	return 0
+}
+
+// TemplateExerciseGroup links template exercises that are done together for a
+// number of rounds. Rest applies between exercises of a round and after each
+// round, replacing the exercises' own rest.
+type TemplateExerciseGroup struct {
+	Base
+	TemplateID                  uint               `json:"template_id" gorm:"not null;index"`
+	GroupType                   string             `json:"group_type" gorm:"size:20;not null"`
+	Name                        string             `json:"name"`
+	Rounds                      int                `json:"rounds" gorm:"not null;default:1"`
+	RestBetweenExercisesSeconds int                `json:"rest_between_exercises_seconds"`
+	RestAfterRoundSeconds       int                `json:"rest_after_round_seconds"`
+	Template                    WorkoutTemplate    `json:"-" gorm:"foreignKey:TemplateID"`
+	Exercises                   []TemplateExercise `json:"exercises" gorm:"foreignKey:GroupID"`
+}
+
+// SessionExerciseGroup is a TemplateExerciseGroup copied into a workout session
+type SessionExerciseGroup struct {
+	Base
+	SessionID                   uint              `json:"session_id" gorm:"not null;index"`
+	GroupType                   string            `json:"group_type" gorm:"size:20;not null"`
+	Name                        string            `json:"name"`
+	Rounds                      int               `json:"rounds" gorm:"not null;default:1"`
+	RestBetweenExercisesSeconds int               `json:"rest_between_exercises_seconds"`
+	RestAfterRoundSeconds       int               `json:"rest_after_round_seconds"`
+	Session                     WorkoutSession    `json:"-" gorm:"foreignKey:SessionID"`
+	Exercises                   []SessionExercise `json:"exercises" gorm:"foreignKey:GroupID"`
+}
+
+func (TemplateExerciseGroup) TableName() string {
+	return "template_exercise_groups"
+}
+
+func (SessionExerciseGroup) TableName() string {
+	return "session_exercise_groups"
+}
@@ -0,0 +1,23 @@
+package models
+
+// ExerciseMedia is an image or video attached to an exercise. The file itself
+// lives in blob storage under StorageKey; URLs are signed on every read.
+type ExerciseMedia struct {
+	Base
+	ExerciseID       uint     `json:"exercise_id" gorm:"not null;index"`
+	UserID           uint     `json:"user_id" gorm:"not null;index"`
+	MediaType        string   `json:"media_type" gorm:"size:10;not null"` // "image" or "video"
+	ContentType      string   `json:"content_type" gorm:"not null"`
+	SizeBytes        int64    `json:"size_bytes" gorm:"not null"`
+	OriginalFilename string   `json:"original_filename"`
+	StorageKey       string   `json:"-" gorm:"not null;uniqueIndex"`
+	ThumbnailKey     string   `json:"-"`
+	URL              string   `json:"url" gorm:"-"`
+	ThumbnailURL     string   `json:"thumbnail_url,omitempty" gorm:"-"`
+	Exercise         Exercise `json:"-" gorm:"foreignKey:ExerciseID"`
+	User             User     `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (ExerciseMedia) TableName() string {
+	return "exercise_media"
+}
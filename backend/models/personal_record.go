@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Personal record types tracked per exercise
+const (
+	RecordHeaviestWeight   = "heaviest_weight"
+	RecordMostRepsAtWeight = "most_reps_at_weight"
+	RecordBestOneRepMax    = "best_estimated_1rm"
+	RecordBestSetVolume    = "best_set_volume"
+	RecordLongestDuration  = "longest_duration"
+	RecordLongestDistance  = "longest_distance"
+)
+
+// PersonalRecord is a set that beat the user's previous best for an exercise.
+// Rows are added as sets are logged and rebuilt whenever logged sets are edited or deleted;
+// IsCurrent marks the record that still stands.
+type PersonalRecord struct {
+	Base
+	UserID        uint      `json:"user_id" gorm:"not null;index:idx_personal_records_user_exercise"`
+	ExerciseID    uint      `json:"exercise_id" gorm:"not null;index:idx_personal_records_user_exercise"`
+	ExerciseSetID uint      `json:"exercise_set_id" gorm:"not null;index"`
+	SessionID     uint      `json:"session_id" gorm:"not null"`
+	RecordType    string    `json:"record_type" gorm:"size:30;not null"`
+	Value         float64   `json:"value" gorm:"not null"`
+	PreviousValue *float64  `json:"previous_value"` // nil for the first time the exercise was done
+	AtWeight      *float64  `json:"at_weight"`      // only for most_reps_at_weight
+	AchievedAt    time.Time `json:"achieved_at" gorm:"not null"`
+	IsCurrent     bool      `json:"is_current" gorm:"default:false"`
+	User          User      `json:"-" gorm:"foreignKey:UserID"`
+	Exercise      *Exercise `json:"exercise,omitempty" gorm:"foreignKey:ExerciseID"`
+}
+
+func (PersonalRecord) TableName() string {
+	return "personal_records"
+}
@@ -0,0 +1,37 @@
+package models
+
+// PlannedSet is a set the user is expected to do in a workout started from a
+// template, with the weight and reps suggested from their last performance.
+// Logging a set fills the next open slot and links the logged set to it.
+type PlannedSet struct {
+	Base
+	SessionExerciseID     uint            `json:"session_exercise_id" gorm:"not null;index"`
+	SetNumber             int             `json:"set_number" gorm:"not null"`
+	TargetReps            *int            `json:"target_reps"`
+	TargetWeight          *float64        `json:"target_weight"` // in kg
+	TargetDurationSeconds *int            `json:"target_duration_seconds"`
+	TargetDistanceMeters  *float64        `json:"target_distance_meters"`
+	TargetRPE             *float64        `json:"target_rpe"`
+	RestSeconds           int             `json:"rest_seconds"`
+	SetType               string          `json:"set_type" gorm:"size:20;default:working"`
+	Completed             bool            `json:"completed" gorm:"default:false"`
+	ExerciseSetID         *uint           `json:"exercise_set_id" gorm:"index"` // the logged set that filled this slot
+	SessionExercise       SessionExercise `json:"-" gorm:"foreignKey:SessionExerciseID"`
+}
+
+// ProgressionIncrement overrides the default weight step for one equipment type
+type ProgressionIncrement struct {
+	Base
+	UserID    uint    `json:"user_id" gorm:"not null;uniqueIndex:idx_progression_increments_user_equipment"`
+	Equipment string  `json:"equipment" gorm:"size:50;not null;uniqueIndex:idx_progression_increments_user_equipment"`
+	Increment float64 `json:"increment"` // in kg, 0 progresses reps only
+	User      User    `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (PlannedSet) TableName() string {
+	return "planned_sets"
+}
+
+func (ProgressionIncrement) TableName() string {
+	return "progression_increments"
+}
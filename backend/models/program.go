@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Program is a multi-week training plan, such as push/pull/legs or 5/3/1, whose
+// days are workout templates
+type Program struct {
+	Base
+	UserID      uint          `json:"user_id" gorm:"not null;index"`
+	Name        string        `json:"name" gorm:"not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	User        User          `json:"-" gorm:"foreignKey:UserID"`
+	Weeks       []ProgramWeek `json:"weeks" gorm:"foreignKey:ProgramID;constraint:OnDelete:CASCADE"`
+}
+
+// ProgramWeek is one week of a program with the progression applied to the workouts
+// started from its days
+type ProgramWeek struct {
+	Base
+	ProgramID   uint         `json:"program_id" gorm:"not null;index"`
+	WeekNumber  int          `json:"week_number" gorm:"not null"`
+	LoadPercent float64      `json:"load_percent"` // planned weights are scaled to this percentage
+	RepChange   int          `json:"rep_change"`   // added to planned reps of working sets
+	Notes       string       `json:"notes" gorm:"type:text"`
+	Program     Program      `json:"-" gorm:"foreignKey:ProgramID"`
+	Days        []ProgramDay `json:"days" gorm:"foreignKey:WeekID;constraint:OnDelete:CASCADE"`
+}
+
+// ProgramDay schedules a template on a day of a program week. Days of the week
+// without one are rest days.
+type ProgramDay struct {
+	Base
+	WeekID     uint             `json:"week_id" gorm:"not null;index"`
+	DayNumber  int              `json:"day_number" gorm:"not null"` // 1-7 within the week
+	TemplateID uint             `json:"template_id" gorm:"not null;index"`
+	Name       string           `json:"name"` // e.g. "Push"; workouts fall back to the template's name
+	Week       ProgramWeek      `json:"-" gorm:"foreignKey:WeekID"`
+	Template   *WorkoutTemplate `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
+
+	// Whether a workout started from this day has been finished during the user's
+	// enrolment; filled in by the program service, not stored
+	Completed bool  `json:"completed" gorm:"-"`
+	SessionID *uint `json:"session_id,omitempty" gorm:"-"` // the finished workout
+}
+
+// ProgramEnrollment is a user following a program from a start date. A user follows
+// one program at a time; EndedAt is set when they leave it or enrol in another.
+type ProgramEnrollment struct {
+	Base
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	ProgramID uint       `json:"program_id" gorm:"not null;index"`
+	StartDate time.Time  `json:"start_date" gorm:"not null"` // midnight UTC of the first day
+	EndedAt   *time.Time `json:"ended_at"`
+	User      User       `json:"-" gorm:"foreignKey:UserID"`
+	Program   Program    `json:"program" gorm:"foreignKey:ProgramID"`
+
+	// Progress through the program; filled in by the program service, not stored
+	CompletedDays int `json:"completed_days" gorm:"-"`
+	TotalDays     int `json:"total_days" gorm:"-"`
+}
+
+func (Program) TableName() string {
+	return "programs"
+}
+
+func (ProgramWeek) TableName() string {
+	return "program_weeks"
+}
+
+func (ProgramDay) TableName() string {
+	return "program_days"
+}
+
+func (ProgramEnrollment) TableName() string {
+	return "program_enrollments"
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// TemplateRotation is an ordered cycle of templates, such as an A/B/C split, for
+// users who train in order rather than on set days. The next template follows the
+// one most recently finished, unless the user skipped or swapped it since.
+type TemplateRotation struct {
+	Base
+	UserID    uint               `json:"user_id" gorm:"not null;index"`
+	Name      string             `json:"name" gorm:"not null"`
+	User      User               `json:"-" gorm:"foreignKey:UserID"`
+	Templates []RotationTemplate `json:"templates" gorm:"foreignKey:RotationID;constraint:OnDelete:CASCADE"`
+
+	// Template chosen as next by skipping or swapping, and when. A workout of the
+	// rotation finished after that takes over again.
+	NextTemplateID *uint      `json:"-"`
+	NextChosenAt   *time.Time `json:"-"`
+}
+
+// RotationTemplate is a template's place in a rotation
+type RotationTemplate struct {
+	Base
+	RotationID uint             `json:"rotation_id" gorm:"not null;index"`
+	TemplateID uint             `json:"template_id" gorm:"not null;index"`
+	Position   int              `json:"position" gorm:"not null"` // 1-based
+	Rotation   TemplateRotation `json:"-" gorm:"foreignKey:RotationID"`
+	Template   *WorkoutTemplate `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
+}
+
+func (TemplateRotation) TableName() string {
+	return "template_rotations"
+}
+
+func (RotationTemplate) TableName() string {
+	return "rotation_templates"
+}
@@ -1,17 +1,28 @@
 package models
 
 import (
+	"log"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func SetupDB() *gorm.DB {
 	// Use SQLite for development
-	db, err := gorm.Open(sqlite.Open("onefit.db"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open("onefit.db"), &gorm.Config{
+		// Constraint violations come back as gorm errors such as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 	if err != nil {
 		panic("Failed to connect database")
 	}
 
+	Migrate(db)
+	return db
+}
+
+// Migrate creates or updates the tables and indexes of every model
+func Migrate(db *gorm.DB) {
 	// Auto create tables - add all the new workout models
 	db.AutoMigrate(
 		&User{},
@@ -19,12 +30,41 @@ func SetupDB() *gorm.DB {
 		&FastSession{},
 		&WaterLog{},
 		&Exercise{},
+		&ExerciseMedia{},
+		&Tag{},
 		&WorkoutTemplate{},
 		&TemplateExercise{},
+		&TemplateSet{},
+		&TemplateExerciseGroup{},
+		&Program{},
+		&ProgramWeek{},
+		&ProgramDay{},
+		&ProgramEnrollment{},
+		&TemplateRotation{},
+		&RotationTemplate{},
 		&WorkoutSession{},
+		&WorkoutPause{},
+		&WorkoutSummary{},
+		&WorkoutSummaryMuscleGroup{},
 		&SessionExercise{},
+		&SessionExerciseGroup{},
 		&ExerciseSet{},
+		&PlannedSet{},
+		&ProgressionIncrement{},
+		&PersonalRecord{},
+		&ScheduledEvent{},
+		&CalendarFeed{},
+		&TemplateRating{},
+		&TemplateSave{},
 	)
+}
 
-	return db
+// CreateIndexes adds the indexes AutoMigrate can't declare. Only one workout per
+// user can be active; workouts left open side by side before this was enforced
+// must be closed first.
+func CreateIndexes(db *gorm.DB) {
+	err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_sessions_active_user ON workout_sessions (user_id) WHERE ended_at IS NULL AND deleted_at IS NULL").Error
+	if err != nil {
+		log.Printf("Warning: Failed to create active workout index: %v", err)
+	}
 }
@@ -0,0 +1,14 @@
+package models
+
+// Tag is a user-defined label for workouts and templates. Names are stored in lower
+// case and are unique per user.
+type Tag struct {
+	Base
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name   string `json:"name" gorm:"size:50;not null;uniqueIndex:idx_tags_user_name"`
+	User   User   `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
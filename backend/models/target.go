@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Target types produced by ParseTarget
+const (
+	TargetReps     = "reps"      // fixed reps, min_reps == max_reps
+	TargetRepRange = "rep_range" // e.g. 8-12
+	TargetAMRAP    = "amrap"     // as many reps as possible
+	TargetTime     = "time"      // hold or work for a duration
+	TargetDistance = "distance"  // cover a distance
+)
+
+// Target is the structured form of TemplateExercise.TargetReps
+type Target struct {
+	Type             string   `json:"type"`
+	MinReps          *int     `json:"min_reps,omitempty"`
+	MaxReps          *int     `json:"max_reps,omitempty"`
+	DurationSeconds  *int     `json:"duration_seconds,omitempty"`
+	DistanceMeters   *float64 `json:"distance_meters,omitempty"`
+	PercentOneRepMax *float64 `json:"percent_one_rep_max,omitempty"` // load as % of 1RM
+	RPE              *float64 `json:"rpe,omitempty"`                 // load as target RPE
+}
+
+var (
+	targetFixedReps = regexp.MustCompile(`^(\d+)$`)
+	targetRepRange  = regexp.MustCompile(`^(\d+)\s*(?:-|–|to)\s*(\d+)$`)
+	targetAMRAP     = regexp.MustCompile(`^(?:amrap|max|max reps)$`)
+	targetClock     = regexp.MustCompile(`^(\d+):([0-5]\d)$`)
+	targetDuration  = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(s|sec|secs|second|seconds|min|mins|minute|minutes|h|hr|hrs|hour|hours)$`)
+	targetDistance  = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(m|meter|meters|metre|metres|km|kilometer|kilometers|kilometre|kilometres|mi|mile|miles|yd|yard|yards)$`)
+	targetPercent   = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*%\s*(?:of\s*)?(?:1rm|e1rm)?$`)
+	targetRPE       = regexp.MustCompile(`^rpe\s*(\d+(?:\.\d+)?)$`)
+	targetRepsWord  = regexp.MustCompile(`\s*(?:reps|rep)$`)
+)
+
+var durationUnits = map[string]float64{
+	"s": 1, "sec": 1, "secs": 1, "second": 1, "seconds": 1,
+	"min": 60, "mins": 60, "minute": 60, "minutes": 60,
+	"h": 3600, "hr": 3600, "hrs": 3600, "hour": 3600, "hours": 3600,
+}
+
+var distanceUnits = map[string]float64{
+	"m": 1, "meter": 1, "meters": 1, "metre": 1, "metres": 1,
+	"km": 1000, "kilometer": 1000, "kilometers": 1000, "kilometre": 1000, "kilometres": 1000,
+	"mi": 1609.344, "mile": 1609.344, "miles": 1609.344,
+	"yd": 0.9144, "yard": 0.9144, "yards": 0.9144,
+}
+
+// ParseTarget turns a free-form target such as "8-12", "AMRAP", "60 sec", "400m",
+// "5 @ 75%" or "8-10 @ RPE 8" into a Target. An empty string means no target.
+func ParseTarget(raw string) (*Target, error) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if value == "" {
+		return nil, nil
+	}
+
+	// Split "volume @ intensity"; "at" is accepted as well
+	volume, intensity := value, ""
+	if index := strings.Index(value, "@"); index >= 0 {
+		volume, intensity = value[:index], value[index+1:]
+	} else if index := strings.Index(value, " at "); index >= 0 {
+		volume, intensity = value[:index], value[index+4:]
+	}
+	volume = strings.TrimSpace(targetRepsWord.ReplaceAllString(strings.TrimSpace(volume), ""))
+	intensity = strings.TrimSpace(intensity)
+
+	target, err := parseTargetVolume(volume)
+	if err != nil {
+		return nil, err
+	}
+
+	if intensity != "" {
+		if err := parseTargetIntensity(intensity, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return target, nil
+}
+
+func parseTargetVolume(volume string) (*Target, error) {
+	if match := targetFixedReps.FindStringSubmatch(volume); match != nil {
+		reps, _ := strconv.Atoi(match[1])
+		if reps <= 0 {
+			return nil, fmt.Errorf("reps must be positive")
+		}
+		return &Target{Type: TargetReps, MinReps: &reps, MaxReps: &reps}, nil
+	}
+
+	if match := targetRepRange.FindStringSubmatch(volume); match != nil {
+		minReps, _ := strconv.Atoi(match[1])
+		maxReps, _ := strconv.Atoi(match[2])
+		if minReps <= 0 || maxReps < minReps {
+			return nil, fmt.Errorf("rep range must be positive and ascending")
+		}
+		if minReps == maxReps {
+			return &Target{Type: TargetReps, MinReps: &minReps, MaxReps: &maxReps}, nil
+		}
+		return &Target{Type: TargetRepRange, MinReps: &minReps, MaxReps: &maxReps}, nil
+	}
+
+	if targetAMRAP.MatchString(volume) {
+		return &Target{Type: TargetAMRAP}, nil
+	}
+
+	if match := targetClock.FindStringSubmatch(volume); match != nil {
+		minutes, _ := strconv.Atoi(match[1])
+		seconds, _ := strconv.Atoi(match[2])
+		duration := minutes*60 + seconds
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration must be positive")
+		}
+		return &Target{Type: TargetTime, DurationSeconds: &duration}, nil
+	}
+
+	if match := targetDuration.FindStringSubmatch(volume); match != nil {
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		duration := int(amount*durationUnits[match[2]] + 0.5)
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration must be positive")
+		}
+		return &Target{Type: TargetTime, DurationSeconds: &duration}, nil
+	}
+
+	if match := targetDistance.FindStringSubmatch(volume); match != nil {
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		distance := amount * distanceUnits[match[2]]
+		if distance <= 0 {
+			return nil, fmt.Errorf("distance must be positive")
+		}
+		return &Target{Type: TargetDistance, DistanceMeters: &distance}, nil
+	}
+
+	return nil, fmt.Errorf("expected reps (8), a range (8-12), AMRAP, a time (60 sec, 1:30) or a distance (400m)")
+}
+
+func parseTargetIntensity(intensity string, target *Target) error {
+	if match := targetPercent.FindStringSubmatch(intensity); match != nil {
+		percent, _ := strconv.ParseFloat(match[1], 64)
+		if percent <= 0 || percent > 150 {
+			return fmt.Errorf("percentage of 1RM must be between 0 and 150")
+		}
+		target.PercentOneRepMax = &percent
+		return nil
+	}
+
+	if match := targetRPE.FindStringSubmatch(intensity); match != nil {
+		rpe, _ := strconv.ParseFloat(match[1], 64)
+		if rpe < 1 || rpe > 10 {
+			return fmt.Errorf("RPE must be between 1 and 10")
+		}
+		target.RPE = &rpe
+		return nil
+	}
+
+	return fmt.Errorf("expected a load such as 75%% or RPE 8 after '@'")
+}
+
+// AfterFind exposes the parsed target alongside the raw string
+func (te *TemplateExercise) AfterFind(tx *gorm.DB) error {
+	te.Target, _ = ParseTarget(te.TargetReps)
+	return nil
+}
+
+// AfterFind parses a set prescription's target the same way
+func (ts *TemplateSet) AfterFind(tx *gorm.DB) error {
+	ts.Target, _ = ParseTarget(ts.TargetReps)
+	return nil
+}
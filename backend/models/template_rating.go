@@ -0,0 +1,28 @@
+package models
+
+// TemplateRating is a user's 1-5 star rating of someone else's public template
+type TemplateRating struct {
+	Base
+	UserID     uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_template_ratings_user_template"`
+	TemplateID uint            `json:"template_id" gorm:"not null;index;uniqueIndex:idx_template_ratings_user_template"`
+	Rating     int             `json:"rating" gorm:"not null"`
+	User       User            `json:"-" gorm:"foreignKey:UserID"`
+	Template   WorkoutTemplate `json:"-" gorm:"foreignKey:TemplateID"`
+}
+
+// TemplateSave bookmarks someone else's public template
+type TemplateSave struct {
+	Base
+	UserID     uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_template_saves_user_template"`
+	TemplateID uint            `json:"template_id" gorm:"not null;index;uniqueIndex:idx_template_saves_user_template"`
+	User       User            `json:"-" gorm:"foreignKey:UserID"`
+	Template   WorkoutTemplate `json:"-" gorm:"foreignKey:TemplateID"`
+}
+
+func (TemplateRating) TableName() string {
+	return "template_ratings"
+}
+
+func (TemplateSave) TableName() string {
+	return "template_saves"
+}
@@ -0,0 +1,23 @@
+package models
+
+// TemplateSet prescribes one set of a template exercise. When a template exercise
+// has prescriptions they replace its TargetSets/TargetReps/TargetWeight shorthand,
+// which allows pyramids and top sets with back-offs. The load is given as at most
+// one of an absolute weight, a percentage of 1RM or a target RPE.
+type TemplateSet struct {
+	Base
+	TemplateExerciseID uint             `json:"template_exercise_id" gorm:"not null;index"`
+	SetNumber          int              `json:"set_number" gorm:"not null"`
+	TargetReps         string           `json:"target_reps"` // same format as TemplateExercise.TargetReps
+	Target             *Target          `json:"target" gorm:"-"`
+	Weight             *float64         `json:"weight"` // in kg
+	PercentOneRepMax   *float64         `json:"percent_one_rep_max"`
+	RPE                *float64         `json:"rpe"`
+	SetType            string           `json:"set_type" gorm:"size:20;default:working"`
+	RestSeconds        *int             `json:"rest_seconds"` // defaults to the exercise's rest
+	TemplateExercise   TemplateExercise `json:"-" gorm:"foreignKey:TemplateExerciseID"`
+}
+
+func (TemplateSet) TableName() string {
+	return "template_sets"
+}
@@ -10,4 +10,6 @@ type User struct {
 	Weight   float64
 	Goals    string `gorm:"type:text"`
 	Settings string `gorm:"type:text"`
+	// Preferred formula for estimated one-rep maxes (empty means the default, epley)
+	OneRepMaxFormula string `gorm:"size:20"`
 }
@@ -4,16 +4,43 @@ import "time"
 
 type WorkoutSession struct {
 	Base
-	UserID          uint              `json:"user_id" gorm:"not null;index"`
-	TemplateID      *uint             `json:"template_id" gorm:"index"`
-	Name            string            `json:"name" gorm:"not null"`
-	StartedAt       time.Time         `json:"started_at" gorm:"not null"`
-	EndedAt         *time.Time        `json:"ended_at"`
-	DurationMinutes *int              `json:"duration_minutes"`
-	Notes           string            `json:"notes" gorm:"type:text"`
-	User            User              `json:"-" gorm:"foreignKey:UserID"`
-	Template        *WorkoutTemplate  `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
-	Exercises       []SessionExercise `json:"exercises" gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	UserID          uint                   `json:"user_id" gorm:"not null;index"`
+	TemplateID      *uint                  `json:"template_id" gorm:"index"`
+	Name            string                 `json:"name" gorm:"not null"`
+	StartedAt       time.Time              `json:"started_at" gorm:"not null"`
+	EndedAt         *time.Time             `json:"ended_at"`
+	DurationMinutes *int                   `json:"duration_minutes"` // active time, kept for older clients
+	ElapsedSeconds  *int                   `json:"elapsed_seconds"`  // wall-clock time from start to end
+	ActiveSeconds   *int                   `json:"active_seconds"`   // elapsed time minus pauses
+	Notes           string                 `json:"notes" gorm:"type:text"`
+	User            User                   `json:"-" gorm:"foreignKey:UserID"`
+	Template        *WorkoutTemplate       `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
+	Exercises       []SessionExercise      `json:"exercises" gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	Groups          []SessionExerciseGroup `json:"groups" gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	Pauses          []WorkoutPause         `json:"pauses" gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	Summary         *WorkoutSummary        `json:"summary,omitempty" gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"` // set once the workout is finished
+	Tags            []Tag                  `json:"tags" gorm:"many2many:workout_session_tags"`
+
+	// Set when the workout was left open and finished by the idle sweeper instead of the user
+	AutoClosed bool `json:"auto_closed" gorm:"default:false"`
+
+	// Set when the workout was started from a day of the user's program; finishing
+	// it completes the day
+	ProgramEnrollmentID *uint `json:"program_enrollment_id" gorm:"index"`
+	ProgramDayID        *uint `json:"program_day_id" gorm:"index"`
+
+	// Whether the workout is paused right now; filled in by the workout service, not stored
+	IsPaused bool `json:"is_paused" gorm:"-"`
+}
+
+// WorkoutPause is an interval during which a workout was paused. ResumedAt is nil
+// while the pause is ongoing.
+type WorkoutPause struct {
+	Base
+	SessionID uint           `json:"session_id" gorm:"not null;index"`
+	PausedAt  time.Time      `json:"paused_at" gorm:"not null"`
+	ResumedAt *time.Time     `json:"resumed_at"`
+	Session   WorkoutSession `json:"-" gorm:"foreignKey:SessionID"`
 }
 
 type SessionExercise struct {
@@ -21,11 +48,14 @@ type SessionExercise struct {
 	SessionID   uint           `json:"session_id" gorm:"not null;index"`
 	ExerciseID  uint           `json:"exercise_id" gorm:"not null;index"`
 	OrderIndex  int            `json:"order_index" gorm:"not null"`
+	GroupID     *uint          `json:"group_id" gorm:"index"` // superset or circuit this exercise belongs to
 	Notes       string         `json:"notes" gorm:"type:text"`
+	Suggestion  string         `json:"suggestion" gorm:"type:text"` // why the planned sets were chosen
 	CompletedAt *time.Time     `json:"completed_at"`
 	Session     WorkoutSession `json:"-" gorm:"foreignKey:SessionID"`
 	Exercise    Exercise       `json:"exercise" gorm:"foreignKey:ExerciseID"`
 	Sets        []ExerciseSet  `json:"sets" gorm:"foreignKey:SessionExerciseID;constraint:OnDelete:CASCADE"`
+	PlannedSets []PlannedSet   `json:"planned_sets" gorm:"foreignKey:SessionExerciseID;constraint:OnDelete:CASCADE"`
 }
 
 type ExerciseSet struct {
@@ -37,14 +67,50 @@ type ExerciseSet struct {
 	DurationSeconds   *int            `json:"duration_seconds"` // for time-based exercises
 	DistanceMeters    *float64        `json:"distance_meters"`  // for cardio
 	RPE               *int            `json:"rpe"`              // Rate of Perceived Exertion (1-10)
+	SetType           string          `json:"set_type" gorm:"size:20;default:working"`
+	ParentSetID       *uint           `json:"parent_set_id" gorm:"index"` // set a drop set continues from
 	CompletedAt       time.Time       `json:"completed_at" gorm:"not null"`
 	SessionExercise   SessionExercise `json:"-" gorm:"foreignKey:SessionExerciseID"`
+	PlannedSet        *PlannedSet     `json:"planned_set,omitempty" gorm:"foreignKey:ExerciseSetID"`
+
+	// Records this set currently holds; filled in by the workout service, not stored
+	PersonalRecords []PersonalRecord `json:"personal_records,omitempty" gorm:"-"`
+
+	// When the prescribed rest after this set ends; filled in when the set is logged
+	RestTimerEndsAt *time.Time `json:"rest_timer_ends_at,omitempty" gorm:"-"`
+}
+
+// Set types; warm-up sets are left out of volume, stats and personal records
+const (
+	SetTypeWarmup    = "warmup"
+	SetTypeWorking   = "working"
+	SetTypeDrop      = "drop"
+	SetTypeFailure   = "failure"
+	SetTypeRestPause = "rest_pause"
+)
+
+// IsValidSetType reports whether setType is a known set type
+func IsValidSetType(setType string) bool {
+	switch setType {
+	case SetTypeWarmup, SetTypeWorking, SetTypeDrop, SetTypeFailure, SetTypeRestPause:
+		return true
+	}
+	return false
+}
+
+// IsWarmup reports whether the set is a warm-up set
+func (s ExerciseSet) IsWarmup() bool {
+	return s.SetType == SetTypeWarmup
 }
 
 func (WorkoutSession) TableName() string {
 	return "workout_sessions"
 }
 
+func (WorkoutPause) TableName() string {
+	return "workout_pauses"
+}
+
 func (SessionExercise) TableName() string {
 	return "session_exercises"
 }
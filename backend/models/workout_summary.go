@@ -0,0 +1,46 @@
+package models
+
+// WorkoutSummary is computed when a workout is finished and kept up to date when its
+// sets change afterwards. Warm-up sets are left out of the volume, set and rep totals.
+type WorkoutSummary struct {
+	Base
+	SessionID           uint                        `json:"session_id" gorm:"not null;uniqueIndex"`
+	TotalVolume         float64                     `json:"total_volume"` // weight × reps in kg
+	TotalSets           int                         `json:"total_sets"`
+	WarmupSets          int                         `json:"warmup_sets"`
+	TotalReps           int                         `json:"total_reps"`
+	ExerciseCount       int                         `json:"exercise_count"` // exercises with at least one set
+	ActiveSeconds       int                         `json:"active_seconds"`
+	EstimatedCalories   float64                     `json:"estimated_calories"`
+	PersonalRecordCount int                         `json:"personal_record_count"`
+	MuscleGroups        []WorkoutSummaryMuscleGroup `json:"muscle_groups" gorm:"foreignKey:SummaryID;constraint:OnDelete:CASCADE"`
+
+	// Change since the previous finished session of the same template; nil without one
+	PreviousSessionID   *uint    `json:"previous_session_id"`
+	VolumeChange        *float64 `json:"volume_change"`
+	VolumeChangePercent *float64 `json:"volume_change_percent"`
+	SetsChange          *int     `json:"sets_change"`
+	RepsChange          *int     `json:"reps_change"`
+	ActiveSecondsChange *int     `json:"active_seconds_change"`
+
+	// Records set during the workout; filled in by the workout service, not stored
+	PersonalRecords []PersonalRecord `json:"personal_records,omitempty" gorm:"-"`
+}
+
+// WorkoutSummaryMuscleGroup is the work done for one muscle group. A set counts fully
+// towards every muscle group its exercise trains.
+type WorkoutSummaryMuscleGroup struct {
+	Base
+	SummaryID   uint    `json:"summary_id" gorm:"not null;index"`
+	MuscleGroup string  `json:"muscle_group" gorm:"size:50;not null"`
+	Sets        int     `json:"sets"`
+	Volume      float64 `json:"volume"`
+}
+
+func (WorkoutSummary) TableName() string {
+	return "workout_summaries"
+}
+
+func (WorkoutSummaryMuscleGroup) TableName() string {
+	return "workout_summary_muscle_groups"
+}
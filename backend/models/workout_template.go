@@ -2,13 +2,38 @@ package models
 
 type WorkoutTemplate struct {
 	Base
-	UserID      uint               `json:"user_id" gorm:"not null;index"`
-	Name        string             `json:"name" gorm:"not null"`
-	Description string             `json:"description" gorm:"type:text"`
-	Category    string             `json:"category"`
-	IsPublic    bool               `json:"is_public" gorm:"default:false"`
-	User        User               `json:"-" gorm:"foreignKey:UserID"`
-	Exercises   []TemplateExercise `json:"exercises" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+	UserID           uint                    `json:"user_id" gorm:"not null;index"`
+	Name             string                  `json:"name" gorm:"not null"`
+	Description      string                  `json:"description" gorm:"type:text"`
+	Category         string                  `json:"category"`
+	IsPublic         bool                    `json:"is_public" gorm:"default:false"`
+	ForkedFromID     *uint                   `json:"forked_from_id" gorm:"index"` // template this one was duplicated from
+	EstimatedMinutes *int                    `json:"-" gorm:"index"`              // kept current for discovery, nil until first computed
+	User             User                    `json:"-" gorm:"foreignKey:UserID"`
+	Exercises        []TemplateExercise      `json:"exercises" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+	Groups           []TemplateExerciseGroup `json:"groups" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+	Tags             []Tag                   `json:"tags" gorm:"many2many:workout_template_tags"`
+	Author           *TemplateAuthor         `json:"author,omitempty" gorm:"-"` // filled in by template discovery, not stored
+	Stats            *TemplateStats          `json:"stats,omitempty" gorm:"-"`  // filled in by template discovery, not stored
+}
+
+// TemplateAuthor is the public face of a template's owner
+type TemplateAuthor struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// TemplateStats describes how a public template has been received
+type TemplateStats struct {
+	AverageRating    *float64 `json:"average_rating"` // nil until rated
+	RatingCount      int      `json:"rating_count"`
+	SaveCount        int      `json:"save_count"`
+	ForkCount        int      `json:"fork_count"`        // users other than the author who duplicated it
+	EstimatedMinutes int      `json:"estimated_minutes"` // from sets, targets and rest
+	MuscleGroups     []string `json:"muscle_groups"`
+	Equipment        []string `json:"equipment"`
+	UserRating       *int     `json:"user_rating"` // the requesting user's rating
+	Saved            bool     `json:"saved"`       // whether the requesting user saved it
 }
 
 type TemplateExercise struct {
@@ -16,12 +41,15 @@ type TemplateExercise struct {
 	TemplateID   uint            `json:"template_id" gorm:"not null;index"`
 	ExerciseID   uint            `json:"exercise_id" gorm:"not null;index"`
 	OrderIndex   int             `json:"order_index" gorm:"not null"`
+	GroupID      *uint           `json:"group_id" gorm:"index"` // superset or circuit this exercise belongs to
 	TargetSets   int             `json:"target_sets"`
-	TargetReps   string          `json:"target_reps"`   // e.g., "8-12", "AMRAP", "60 sec"
-	TargetWeight *float64        `json:"target_weight"` // in kg
+	TargetReps   string          `json:"target_reps"`     // e.g., "8-12", "AMRAP", "60 sec"
+	Target       *Target         `json:"target" gorm:"-"` // parsed form of TargetReps
+	TargetWeight *float64        `json:"target_weight"`   // in kg
 	RestSeconds  int             `json:"rest_seconds"`
 	Template     WorkoutTemplate `json:"-" gorm:"foreignKey:TemplateID"`
 	Exercise     Exercise        `json:"exercise" gorm:"foreignKey:ExerciseID"`
+	Sets         []TemplateSet   `json:"sets" gorm:"foreignKey:TemplateExerciseID;constraint:OnDelete:CASCADE"` // per-set prescriptions
 }
 
 func (WorkoutTemplate) TableName() string {
@@ -80,25 +80,71 @@ const (
 		GROUP BY wt.id, wt.name, wt.description, wt.category, wt.is_public, wt.created_at
 		ORDER BY wt.created_at DESC`
 
+	// Workout history without sets: finished workouts read their stored summary,
+	// the rest are totalled with GetWorkoutExerciseTotals.
+	// Args: user ID, subquery of the matching workout IDs, limit, offset.
 	GetUserWorkoutHistory = `
 		SELECT 
 			ws.id,
 			ws.name,
+			ws.template_id,
+			wt.name as template_name,
 			ws.started_at,
 			ws.ended_at,
-			ws.duration_minutes,
-			ws.notes,
-			wt.name as template_name,
-			COUNT(DISTINCT se.id) as exercises_performed,
-			COUNT(es.id) as total_sets
+			COALESCE(ws.active_seconds, ws.duration_minutes * 60) as active_seconds,
+			ws.auto_closed,
+			sm.id as summary_id,
+			COALESCE(sm.exercise_count, 0) as exercise_count,
+			COALESCE(sm.total_sets, 0) as total_sets,
+			COALESCE(sm.total_volume, 0) as total_volume
 		FROM workout_sessions ws
-		LEFT JOIN workout_templates wt ON ws.template_id = wt.id
-		LEFT JOIN session_exercises se ON ws.id = se.session_id AND se.deleted_at IS NULL
-		LEFT JOIN exercise_sets es ON se.id = es.session_exercise_id AND es.deleted_at IS NULL
+		LEFT JOIN workout_templates wt ON ws.template_id = wt.id AND wt.deleted_at IS NULL
+		LEFT JOIN workout_summaries sm ON ws.id = sm.session_id AND sm.deleted_at IS NULL
 		WHERE ws.user_id = ? 
 			AND ws.deleted_at IS NULL
-		GROUP BY ws.id, ws.name, ws.started_at, ws.ended_at, ws.duration_minutes, ws.notes, wt.name
-		ORDER BY ws.started_at DESC`
+			AND ws.id IN (?)
+		ORDER BY ws.started_at DESC
+		LIMIT ? OFFSET ?`
+
+	// Sets and volume per session exercise for a list of workouts, warm-ups excluded
+	// from working_sets and volume.
+	// Args: warm-up set type twice, session IDs.
+	GetWorkoutExerciseTotals = `
+		SELECT 
+			se.session_id,
+			se.id as session_exercise_id,
+			e.muscle_groups,
+			COUNT(es.id) as logged_sets,
+			COUNT(CASE WHEN es.set_type IS NULL OR es.set_type <> ? THEN es.id END) as working_sets,
+			COALESCE(SUM(CASE WHEN es.set_type IS NULL OR es.set_type <> ? THEN es.weight * es.reps END), 0) as volume
+		FROM session_exercises se
+		INNER JOIN exercises e ON se.exercise_id = e.id
+		LEFT JOIN exercise_sets es ON se.id = es.session_exercise_id AND es.deleted_at IS NULL
+		WHERE se.session_id IN ? 
+			AND se.deleted_at IS NULL
+		GROUP BY se.session_id, se.id, e.muscle_groups`
+
+	// Args: user ID.
+	GetUserTagsWithUsage = `
+		SELECT 
+			t.id,
+			t.name,
+			(
+				SELECT COUNT(*)
+				FROM workout_session_tags wst
+				INNER JOIN workout_sessions ws ON wst.workout_session_id = ws.id AND ws.deleted_at IS NULL
+				WHERE wst.tag_id = t.id
+			) as workout_count,
+			(
+				SELECT COUNT(*)
+				FROM workout_template_tags wtt
+				INNER JOIN workout_templates wt ON wtt.workout_template_id = wt.id AND wt.deleted_at IS NULL
+				WHERE wtt.tag_id = t.id
+			) as template_count
+		FROM tags t
+		WHERE t.user_id = ? 
+			AND t.deleted_at IS NULL
+		ORDER BY t.name ASC`
 
 	GetUserExercises = `
 		-- Custom exercises created by user
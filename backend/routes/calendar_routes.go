@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"onefit/backend/controllers"
+	"onefit/backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupCalendarRoutes(router *gin.Engine, db *gorm.DB) {
+	calendarController := controllers.NewCalendarController(db)
+
+	// Calendar routes group
+	calendar := router.Group("/api/calendar")
+	calendar.Use(middleware.AuthMiddleware(db))
+	{
+		// Calendar view
+		calendar.GET("/", calendarController.GetCalendar) // Occurrences between two dates with status
+
+		// Scheduled event CRUD operations
+		calendar.GET("/events", calendarController.GetEvents)          // List scheduled events
+		calendar.POST("/events", calendarController.CreateEvent)       // Schedule workout, fast or rest day
+		calendar.PUT("/events/:id", calendarController.UpdateEvent)    // Replace scheduled event
+		calendar.DELETE("/events/:id", calendarController.DeleteEvent) // Delete event and its occurrences
+
+		// iCalendar subscription
+		calendar.GET("/feed", calendarController.GetFeed)          // Subscription URL, created on first use
+		calendar.POST("/feed/reset", calendarController.ResetFeed) // Replace subscription URL
+		calendar.DELETE("/feed", calendarController.DisableFeed)   // Turn off subscription URL
+	}
+
+	// Public feed; the token in the URL authorizes it
+	router.GET("/calendar/:file", calendarController.ServeFeed) // <token>.ics
+}
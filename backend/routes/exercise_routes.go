@@ -21,5 +21,19 @@ func SetupExerciseRoutes(router *gin.Engine, db *gorm.DB) {
 		exercises.POST("/", exerciseController.CreateExercise)      // Create custom exercise
 		exercises.PUT("/:id", exerciseController.UpdateExercise)    // Update custom exercise
 		exercises.DELETE("/:id", exerciseController.DeleteExercise) // Delete custom exercise
+
+		// Performance tracking
+		exercises.GET("/:id/history", exerciseController.GetExerciseHistory) // Per-session history with chart series
+		exercises.GET("/:id/records", exerciseController.GetPersonalRecords) // Personal records for the exercise
+		exercises.GET("/:id/rest", exerciseController.GetExerciseRest)       // Rest before its sets across recent workouts
+
+		// Duplicate cleanup
+		exercises.POST("/:id/merge", exerciseController.MergeExercise) // Merge custom exercise into another (supports dry run)
+
+		// Exercise media attachments
+		exercises.GET("/:id/media", exerciseController.GetExerciseMedia)                 // List media with signed URLs
+		exercises.POST("/:id/media/images", exerciseController.UploadExerciseImage)      // Upload demo image (multipart)
+		exercises.POST("/:id/media/videos", exerciseController.UploadExerciseVideo)      // Upload demo video (multipart)
+		exercises.DELETE("/:id/media/:media_id", exerciseController.DeleteExerciseMedia) // Delete media attachment
 	}
 }
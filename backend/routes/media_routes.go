@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"onefit/backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupMediaRoutes(router *gin.Engine) {
+	mediaController := controllers.NewMediaController()
+
+	// Public route - access is granted by the signature in the URL, not the auth header
+	router.GET("/media/*key", mediaController.ServeMedia)
+}
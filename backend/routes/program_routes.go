@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"onefit/backend/controllers"
+	"onefit/backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupProgramRoutes(router *gin.Engine, db *gorm.DB) {
+	programController := controllers.NewProgramController(db)
+
+	// Program routes group
+	programs := router.Group("/api/programs")
+	programs.Use(middleware.AuthMiddleware(db))
+	{
+		// Program CRUD operations
+		programs.GET("/", programController.GetPrograms)         // List the user's programs
+		programs.GET("/:id", programController.GetProgram)       // Get program with weeks and days
+		programs.POST("/", programController.CreateProgram)      // Create program from templates
+		programs.PUT("/:id", programController.UpdateProgram)    // Update program or replace its weeks
+		programs.DELETE("/:id", programController.DeleteProgram) // Delete program
+
+		// Enrolment
+		programs.POST("/:id/enroll", programController.Enroll)         // Follow a program from a start date
+		programs.GET("/enrollment", programController.GetEnrollment)   // Current program with completed days
+		programs.DELETE("/enrollment", programController.LeaveProgram) // Stop following the program
+		programs.GET("/today", programController.GetTodaysWorkout)     // Workout planned for today
+	}
+}
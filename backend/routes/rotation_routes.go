@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"onefit/backend/controllers"
+	"onefit/backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupRotationRoutes(router *gin.Engine, db *gorm.DB) {
+	rotationController := controllers.NewRotationController(db)
+
+	// Template rotation routes group
+	rotations := router.Group("/api/rotations")
+	rotations.Use(middleware.AuthMiddleware(db))
+	{
+		// Rotation CRUD operations
+		rotations.GET("/", rotationController.GetRotations)         // List the user's rotations
+		rotations.GET("/:id", rotationController.GetRotation)       // Get rotation with templates in order
+		rotations.POST("/", rotationController.CreateRotation)      // Create rotation from templates
+		rotations.PUT("/:id", rotationController.UpdateRotation)    // Rename or replace templates
+		rotations.DELETE("/:id", rotationController.DeleteRotation) // Delete rotation
+
+		// What's next
+		rotations.GET("/:id/next", rotationController.GetNextTemplate) // Template due next
+		rotations.POST("/:id/skip", rotationController.SkipNext)       // Skip the next template
+		rotations.POST("/:id/swap", rotationController.SwapNext)       // Choose another template as next
+	}
+}
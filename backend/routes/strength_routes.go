@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"onefit/backend/controllers"
+	"onefit/backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupStrengthRoutes(router *gin.Engine, db *gorm.DB) {
+	strengthController := controllers.NewStrengthController(db)
+
+	// Strength calculator routes group
+	strength := router.Group("/api/strength")
+	strength.Use(middleware.AuthMiddleware(db))
+	{
+		// Formula selection
+		strength.GET("/formulas", strengthController.GetFormulas)          // List formulas and the user's selection
+		strength.PUT("/preferences", strengthController.UpdatePreferences) // Change preferred formula
+
+		// Progressive overload
+		strength.GET("/progression-increments", strengthController.GetProgressionIncrements)    // Weight step per equipment type
+		strength.PUT("/progression-increments", strengthController.UpdateProgressionIncrements) // Override weight steps
+
+		// Estimated one-rep max
+		strength.POST("/one-rep-max", strengthController.CalculateOneRepMax)      // Estimate from weight and reps
+		strength.GET("/workouts/:id", strengthController.GetWorkoutOneRepMax)     // Per-set and per-exercise estimates
+		strength.GET("/exercises/:id/trend", strengthController.GetExerciseTrend) // e1RM progression over time
+	}
+}
@@ -16,15 +16,31 @@ func SetupTemplateRoutes(router *gin.Engine, db *gorm.DB) {
 	templates.Use(middleware.AuthMiddleware(db))
 	{
 		// Template CRUD operations
-		templates.GET("/", templateController.GetTemplates)         // List templates with filters
-		templates.GET("/:id", templateController.GetTemplate)       // Get single template with exercises
-		templates.POST("/", templateController.CreateTemplate)      // Create new template
-		templates.PUT("/:id", templateController.UpdateTemplate)    // Update template
-		templates.DELETE("/:id", templateController.DeleteTemplate) // Delete template
+		templates.GET("/", templateController.GetTemplates)                           // List templates with filters
+		templates.GET("/:id", templateController.GetTemplate)                         // Get single template with exercises
+		templates.POST("/", templateController.CreateTemplate)                        // Create new template
+		templates.POST("/from-workout", templateController.CreateTemplateFromWorkout) // Save a finished workout as a template
+		templates.PUT("/:id", templateController.UpdateTemplate)                      // Update template
+		templates.DELETE("/:id", templateController.DeleteTemplate)                   // Delete template
 
 		// Template-Exercise management
 		templates.POST("/:id/exercises", templateController.AddExerciseToTemplate)                     // Add exercise to template
+		templates.PUT("/:id/exercises/order", templateController.ReorderTemplateExercises)             // Reorder all exercises
 		templates.PUT("/:id/exercises/:exercise_id", templateController.UpdateTemplateExercise)        // Update exercise in template
 		templates.DELETE("/:id/exercises/:exercise_id", templateController.RemoveExerciseFromTemplate) // Remove exercise from template
+
+		// Supersets and circuits
+		templates.POST("/:id/groups", templateController.CreateTemplateGroup)             // Group exercises
+		templates.PUT("/:id/groups/:group_id", templateController.UpdateTemplateGroup)    // Update group type, rounds or rest
+		templates.DELETE("/:id/groups/:group_id", templateController.DeleteTemplateGroup) // Ungroup exercises
+
+		// Public template discovery
+		templates.GET("/discover", templateController.DiscoverTemplates)       // Search public templates with ratings and forks
+		templates.GET("/saved", templateController.GetSavedTemplates)          // List saved public templates
+		templates.POST("/:id/duplicate", templateController.DuplicateTemplate) // Copy own or public template
+		templates.PUT("/:id/rating", templateController.RateTemplate)          // Rate public template 1-5
+		templates.DELETE("/:id/rating", templateController.RemoveRating)       // Remove rating
+		templates.POST("/:id/save", templateController.SaveTemplate)           // Save public template
+		templates.DELETE("/:id/save", templateController.UnsaveTemplate)       // Remove from saved
 	}
 }
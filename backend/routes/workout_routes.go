@@ -18,17 +18,37 @@ func SetupWorkoutRoutes(router *gin.Engine, db *gorm.DB) {
 		// Workout CRUD operations
 		workouts.GET("/", workoutController.GetWorkouts)            // Get user's workout history
 		workouts.POST("/", workoutController.StartWorkout)          // Start a new workout
+		workouts.POST("/past", workoutController.LogPastWorkout)    // Log a finished workout after the fact
 		workouts.GET("/active", workoutController.GetActiveWorkout) // Get current active workout
 		workouts.GET("/stats", workoutController.GetWorkoutStats)   // Get workout statistics
 		workouts.GET("/:id", workoutController.GetWorkout)          // Get specific workout details
 		workouts.PUT("/:id", workoutController.UpdateWorkout)       // Update/finish workout
 		workouts.DELETE("/:id", workoutController.DeleteWorkout)    // Delete workout
+		workouts.GET("/:id/rest", workoutController.GetWorkoutRest) // Get rest between sets
+
+		// Tags shared by workouts and templates
+		workouts.GET("/tags", workoutController.GetTags) // List the user's tags with usage counts
+
+		// Completion summary, computed when a workout is finished
+		workouts.GET("/:id/summary", workoutController.GetWorkoutSummary) // Get summary of a finished workout
+
+		// Pausing and resuming
+		workouts.POST("/:id/pause", workoutController.PauseWorkout)   // Pause an ongoing workout
+		workouts.POST("/:id/resume", workoutController.ResumeWorkout) // Resume a paused workout
+
+		// Repeating a previous workout
+		workouts.POST("/:id/repeat", workoutController.RepeatWorkout) // Start a new workout cloned from this one
 
 		// Exercise management within workouts
 		workouts.POST("/:id/exercises", workoutController.AddExerciseToWorkout)                     // Add exercise to workout
+		workouts.PUT("/:id/exercises/order", workoutController.ReorderWorkoutExercises)             // Reorder all exercises
 		workouts.PUT("/:id/exercises/:exercise_id", workoutController.UpdateSessionExercise)        // Update exercise in workout
 		workouts.DELETE("/:id/exercises/:exercise_id", workoutController.RemoveExerciseFromWorkout) // Remove exercise from workout
 
+		// Supersets and circuits
+		workouts.POST("/:id/groups", workoutController.CreateWorkoutGroup)             // Group exercises
+		workouts.DELETE("/:id/groups/:group_id", workoutController.DeleteWorkoutGroup) // Ungroup exercises
+
 		// Set logging and management
 		workouts.POST("/:id/exercises/:exercise_id/sets", workoutController.LogSet) // Log a set for an exercise
 		workouts.PUT("/:id/sets/:set_id", workoutController.UpdateSet)              // Update a logged set
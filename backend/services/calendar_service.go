@@ -0,0 +1,405 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"onefit/backend/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidEvent         = errors.New("invalid scheduled event")
+	ErrInvalidCalendarRange = errors.New("invalid calendar range")
+)
+
+const (
+	maxCalendarRangeDays   = 366
+	maxFastTargetHours     = 168
+	defaultWorkoutDuration = 60 // minutes, for timed workout events without a duration
+)
+
+// Statuses of a calendar occurrence
+const (
+	OccurrencePlanned   = "planned"   // today or later and not done yet
+	OccurrenceCompleted = "completed" // linked to the workout or fast that fulfilled it; rest days without a workout
+	OccurrenceMissed    = "missed"    // past with nothing logged; rest days with a workout
+)
+
+type CalendarService struct {
+	db *gorm.DB
+}
+
+func NewCalendarService(db *gorm.DB) *CalendarService {
+	return &CalendarService{db: db}
+}
+
+// ScheduledEventInput describes an event being created or updated
+type ScheduledEventInput struct {
+	EventType       string
+	Title           string
+	TemplateID      *uint
+	TargetHours     *int
+	StartDate       time.Time
+	StartTime       string
+	DurationMinutes int
+	Recurrence      string
+	Notes           string
+}
+
+// CalendarOccurrence is one date a scheduled event falls on, with what fulfilled it
+type CalendarOccurrence struct {
+	EventID     uint   `json:"event_id"`
+	Date        string `json:"date"` // YYYY-MM-DD
+	StartTime   string `json:"start_time,omitempty"`
+	EventType   string `json:"event_type"`
+	Title       string `json:"title"`
+	TemplateID  *uint  `json:"template_id,omitempty"`
+	TargetHours *int   `json:"target_hours,omitempty"`
+	Status      string `json:"status"`
+	SessionID   *uint  `json:"session_id,omitempty"` // workout that fulfilled a workout event
+	FastID      *uint  `json:"fast_id,omitempty"`    // fast that fulfilled a fast event
+	TargetMet   *bool  `json:"target_met,omitempty"` // whether that fast reached the target
+	Recurring   bool   `json:"recurring"`
+	Notes       string `json:"notes,omitempty"`
+}
+
+// GetEvents lists the user's scheduled events by start date
+func (cs *CalendarService) GetEvents(userID uint) ([]models.ScheduledEvent, error) {
+	events := []models.ScheduledEvent{}
+	err := cs.db.Where("user_id = ?", userID).
+		Preload("Template").
+		Order("start_date ASC, start_time ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// CreateEvent schedules a workout, fast or rest day
+func (cs *CalendarService) CreateEvent(userID uint, input ScheduledEventInput) (*models.ScheduledEvent, error) {
+	event := models.ScheduledEvent{UserID: userID}
+	if err := cs.applyEventInput(&event, input); err != nil {
+		return nil, err
+	}
+
+	if err := cs.db.Omit("Template").Create(&event).Error; err != nil {
+		return nil, err
+	}
+	return cs.getEvent(userID, event.ID)
+}
+
+// UpdateEvent replaces a scheduled event's details
+func (cs *CalendarService) UpdateEvent(userID, eventID uint, input ScheduledEventInput) (*models.ScheduledEvent, error) {
+	event, err := cs.getEvent(userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.applyEventInput(event, input); err != nil {
+		return nil, err
+	}
+
+	if err := cs.db.Omit("Template").Save(event).Error; err != nil {
+		return nil, err
+	}
+	return cs.getEvent(userID, event.ID)
+}
+
+// DeleteEvent removes a scheduled event with all its occurrences
+func (cs *CalendarService) DeleteEvent(userID, eventID uint) error {
+	event, err := cs.getEvent(userID, eventID)
+	if err != nil {
+		return err
+	}
+	return cs.db.Delete(event).Error
+}
+
+// getEvent loads one of the user's scheduled events
+func (cs *CalendarService) getEvent(userID, eventID uint) (*models.ScheduledEvent, error) {
+	var event models.ScheduledEvent
+	err := cs.db.Where("id = ? AND user_id = ?", eventID, userID).
+		Preload("Template").
+		First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// applyEventInput validates an event's details and sets them on the event
+func (cs *CalendarService) applyEventInput(event *models.ScheduledEvent, input ScheduledEventInput) error {
+	title := strings.TrimSpace(input.Title)
+
+	event.TemplateID = nil
+	event.TargetHours = nil
+	event.Template = nil
+
+	switch input.EventType {
+	case models.EventTypeWorkout:
+		if input.TemplateID == nil {
+			return fmt.Errorf("%w: workout events need a template_id", ErrInvalidEvent)
+		}
+		var template models.WorkoutTemplate
+		err := cs.db.Where("id = ? AND (user_id = ? OR is_public = ?)", *input.TemplateID, event.UserID, true).First(&template).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: template not found or not accessible", ErrInvalidEvent)
+		}
+		if err != nil {
+			return err
+		}
+		event.TemplateID = &template.ID
+		if title == "" {
+			title = template.Name
+		}
+	case models.EventTypeFast:
+		if input.TargetHours == nil || *input.TargetHours < 1 || *input.TargetHours > maxFastTargetHours {
+			return fmt.Errorf("%w: fast events need target_hours between 1 and %d", ErrInvalidEvent, maxFastTargetHours)
+		}
+		event.TargetHours = input.TargetHours
+		if title == "" {
+			title = fmt.Sprintf("%dh fast", *input.TargetHours)
+		}
+	case models.EventTypeRest:
+		if title == "" {
+			title = "Rest day"
+		}
+	default:
+		return fmt.Errorf("%w: event_type must be workout, fast or rest", ErrInvalidEvent)
+	}
+
+	if input.StartTime != "" {
+		if _, err := time.Parse("15:04", input.StartTime); err != nil {
+			return fmt.Errorf("%w: start_time must be HH:MM", ErrInvalidEvent)
+		}
+	}
+	if input.DurationMinutes < 0 || input.DurationMinutes > 24*60 {
+		return fmt.Errorf("%w: duration_minutes must be between 0 and 1440", ErrInvalidEvent)
+	}
+
+	rule, err := parseRecurrence(input.Recurrence)
+	if err != nil {
+		return err
+	}
+
+	// Calendar apps always count the start as the first occurrence, so a weekly
+	// rule's start moves to its first BYDAY day; COUNT then agrees as well
+	startDate := programDate(input.StartDate)
+	if rule != nil && len(rule.ByDay) > 0 {
+		first := rule.occurrencesBetween(startDate, startDate, startDate.AddDate(0, 0, 7*rule.Interval+6))
+		if len(first) == 0 {
+			return fmt.Errorf("%w: the recurrence has no dates from start_date on", ErrInvalidEvent)
+		}
+		startDate = first[0]
+	}
+
+	event.EventType = input.EventType
+	event.Title = title
+	event.StartDate = startDate
+	event.StartTime = input.StartTime
+	event.DurationMinutes = input.DurationMinutes
+	event.Recurrence = ""
+	if rule != nil {
+		event.Recurrence = rule.String(false)
+	}
+	event.Notes = input.Notes
+	return nil
+}
+
+// GetCalendar expands the user's events into occurrences from one date through
+// another, linking each to the workout or fast that fulfilled it. Workouts and fasts
+// belong to the date they started on in loc, the user's time zone.
+func (cs *CalendarService) GetCalendar(userID uint, from, to time.Time, loc *time.Location, now time.Time) ([]CalendarOccurrence, error) {
+	from, to = programDate(from), programDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidCalendarRange)
+	}
+	if to.Sub(from).Hours()/24 >= maxCalendarRangeDays {
+		return nil, fmt.Errorf("%w: at most %d days can be requested", ErrInvalidCalendarRange, maxCalendarRangeDays)
+	}
+
+	var events []models.ScheduledEvent
+	err := cs.db.Where("user_id = ? AND start_date <= ?", userID, to).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Local midnight of the first day through local midnight after the last one
+	rangeStart := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc).UTC()
+	rangeEnd := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc).UTC()
+
+	var workouts []models.WorkoutSession
+	err = cs.db.Select("id", "template_id", "started_at").
+		Where("user_id = ? AND started_at >= ? AND started_at < ?", userID, rangeStart, rangeEnd).
+		Order("started_at ASC").
+		Find(&workouts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var fasts []models.FastSession
+	err = cs.db.Where("user_id = ? AND start_time >= ? AND start_time < ?", userID, rangeStart, rangeEnd).
+		Order("start_time ASC").
+		Find(&fasts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	localDate := func(t time.Time) string {
+		return t.In(loc).Format("2006-01-02")
+	}
+	workoutsByDate := make(map[string][]models.WorkoutSession)
+	for _, workout := range workouts {
+		date := localDate(workout.StartedAt)
+		workoutsByDate[date] = append(workoutsByDate[date], workout)
+	}
+	fastsByDate := make(map[string][]models.FastSession)
+	for _, fast := range fasts {
+		date := localDate(fast.StartTime)
+		fastsByDate[date] = append(fastsByDate[date], fast)
+	}
+	today := localDate(now)
+
+	// Each workout or fast fulfils one occurrence at most
+	usedWorkouts := make(map[uint]bool)
+	usedFasts := make(map[uint]bool)
+
+	occurrences := []CalendarOccurrence{}
+	for _, event := range events {
+		rule, err := parseRecurrence(event.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, date := range rule.occurrencesBetween(event.StartDate, from, to) {
+			occurrence := CalendarOccurrence{
+				EventID:     event.ID,
+				Date:        date.Format("2006-01-02"),
+				StartTime:   event.StartTime,
+				EventType:   event.EventType,
+				Title:       event.Title,
+				TemplateID:  event.TemplateID,
+				TargetHours: event.TargetHours,
+				Status:      OccurrencePlanned,
+				Recurring:   rule != nil,
+				Notes:       event.Notes,
+			}
+
+			switch event.EventType {
+			case models.EventTypeWorkout:
+				for _, workout := range workoutsByDate[occurrence.Date] {
+					if !usedWorkouts[workout.ID] && workout.TemplateID != nil && *workout.TemplateID == *event.TemplateID {
+						usedWorkouts[workout.ID] = true
+						occurrence.SessionID = &workout.ID
+						occurrence.Status = OccurrenceCompleted
+						break
+					}
+				}
+			case models.EventTypeFast:
+				for _, fast := range fastsByDate[occurrence.Date] {
+					if !usedFasts[fast.ID] {
+						usedFasts[fast.ID] = true
+						targetMet := fast.Duration >= *event.TargetHours*60
+						occurrence.FastID = &fast.ID
+						occurrence.TargetMet = &targetMet
+						occurrence.Status = OccurrenceCompleted
+						break
+					}
+				}
+			case models.EventTypeRest:
+				if len(workoutsByDate[occurrence.Date]) > 0 {
+					occurrence.Status = OccurrenceMissed
+				} else if occurrence.Date < today {
+					occurrence.Status = OccurrenceCompleted
+				}
+			}
+
+			if occurrence.Status == OccurrencePlanned && occurrence.Date < today {
+				occurrence.Status = OccurrenceMissed
+			}
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	// All-day events first, then by start time; HH:MM times sort as strings
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		a, b := occurrences[i], occurrences[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+		return a.EventID < b.EventID
+	})
+	return occurrences, nil
+}
+
+// GetFeedToken returns the secret token of the user's calendar feed, creating one
+// on first use
+func (cs *CalendarService) GetFeedToken(userID uint) (string, error) {
+	var feed models.CalendarFeed
+	err := cs.db.Where("user_id = ?", userID).First(&feed).Error
+	if err == nil {
+		return feed.Token, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return cs.ResetFeedToken(userID)
+}
+
+// ResetFeedToken replaces the user's feed token, so the old subscription URL stops
+// working
+func (cs *CalendarService) ResetFeedToken(userID uint) (string, error) {
+	token, err := newFeedToken()
+	if err != nil {
+		return "", err
+	}
+
+	// A feed disabled before is brought back rather than clashing on user_id
+	var feeds []models.CalendarFeed
+	if err := cs.db.Unscoped().Where("user_id = ?", userID).Limit(1).Find(&feeds).Error; err != nil {
+		return "", err
+	}
+	if len(feeds) == 0 {
+		err = cs.db.Create(&models.CalendarFeed{UserID: userID, Token: token}).Error
+	} else {
+		err = cs.db.Unscoped().Model(&feeds[0]).Updates(map[string]interface{}{"token": token, "deleted_at": nil}).Error
+	}
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// DisableFeed turns off the user's calendar feed
+func (cs *CalendarService) DisableFeed(userID uint) error {
+	return cs.db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{}).Error
+}
+
+// newFeedToken generates a random token for a feed URL
+func newFeedToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GetFeed returns the iCalendar feed of the user a token belongs to
+func (cs *CalendarService) GetFeed(token string) (string, error) {
+	var feed models.CalendarFeed
+	if err := cs.db.Where("token = ?", token).First(&feed).Error; err != nil {
+		return "", err
+	}
+
+	events, err := cs.GetEvents(feed.UserID)
+	if err != nil {
+		return "", err
+	}
+	return buildICalendar(events, time.Now()), nil
+}
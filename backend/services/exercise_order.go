@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"onefit/backend/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidOrder = errors.New("invalid exercise order")
+
+// Exercises of a template or workout are numbered 1..n by order_index. Every change
+// rewrites the whole sequence in one transaction so positions never collide or
+// leave gaps.
+
+// moveID moves id to a 1-based position in ids, clamped to the list
+func moveID(ids []uint, id uint, position int) []uint {
+	moved := make([]uint, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			moved = append(moved, existing)
+		}
+	}
+
+	index := min(max(position-1, 0), len(moved))
+	moved = append(moved, 0)
+	copy(moved[index+1:], moved[index:])
+	moved[index] = id
+	return moved
+}
+
+// isPermutation reports whether ordered holds exactly the IDs in current, once each
+func isPermutation(current, ordered []uint) bool {
+	if len(current) != len(ordered) {
+		return false
+	}
+
+	remaining := make(map[uint]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range ordered {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
+// templateExerciseOrder returns the IDs of a template's exercises in order
+func templateExerciseOrder(tx *gorm.DB, templateID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.TemplateExercise{}).
+		Where("template_id = ?", templateID).
+		Order("order_index ASC, id ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// writeTemplateExerciseOrder numbers template exercises 1..n in the given order
+func writeTemplateExerciseOrder(tx *gorm.DB, ids []uint) error {
+	for i, id := range ids {
+		err := tx.Model(&models.TemplateExercise{}).Where("id = ?", id).Update("order_index", i+1).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveTemplateExercise moves a template exercise to a position and renumbers the rest
+func moveTemplateExercise(tx *gorm.DB, templateID, templateExerciseID uint, position int) error {
+	ids, err := templateExerciseOrder(tx, templateID)
+	if err != nil {
+		return err
+	}
+	return writeTemplateExerciseOrder(tx, moveID(ids, templateExerciseID, position))
+}
+
+// compactTemplateExerciseOrder closes gaps left by removed template exercises
+func compactTemplateExerciseOrder(tx *gorm.DB, templateID uint) error {
+	ids, err := templateExerciseOrder(tx, templateID)
+	if err != nil {
+		return err
+	}
+	return writeTemplateExerciseOrder(tx, ids)
+}
+
+// sessionExerciseOrder returns the IDs of a workout's exercises in order
+func sessionExerciseOrder(tx *gorm.DB, sessionID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.SessionExercise{}).
+		Where("session_id = ?", sessionID).
+		Order("order_index ASC, id ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// writeSessionExerciseOrder numbers session exercises 1..n in the given order
+func writeSessionExerciseOrder(tx *gorm.DB, ids []uint) error {
+	for i, id := range ids {
+		err := tx.Model(&models.SessionExercise{}).Where("id = ?", id).Update("order_index", i+1).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveSessionExercise moves a session exercise to a position and renumbers the rest
+func moveSessionExercise(tx *gorm.DB, sessionID, sessionExerciseID uint, position int) error {
+	ids, err := sessionExerciseOrder(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	return writeSessionExerciseOrder(tx, moveID(ids, sessionExerciseID, position))
+}
+
+// compactSessionExerciseOrder closes gaps left by removed session exercises
+func compactSessionExerciseOrder(tx *gorm.DB, sessionID uint) error {
+	ids, err := sessionExerciseOrder(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	return writeSessionExerciseOrder(tx, ids)
+}
@@ -1,13 +1,19 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"onefit/backend/models"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrMergeSameExercise = errors.New("cannot merge an exercise into itself")
+)
+
 type ExerciseService struct {
 	db *gorm.DB
 }
@@ -179,6 +185,384 @@ func (es *ExerciseService) DeleteCustomExercise(userID, exerciseID uint) error {
 	return es.db.Delete(&exercise).Error
 }
 
+// ExerciseMergePreview describes what merging one exercise into another will change
+type ExerciseMergePreview struct {
+	SourceExercise      models.Exercise `json:"source_exercise"`
+	TargetExercise      models.Exercise `json:"target_exercise"`
+	TemplateExercises   int64           `json:"template_exercises"` // template rows re-pointed to the target
+	TemplateConflicts   int64           `json:"template_conflicts"` // template rows dropped because the template already has the target
+	SessionExercises    int64           `json:"session_exercises"`  // session rows re-pointed to the target
+	Media               int64           `json:"media"`              // images and videos moved to the target
+	AffectedTemplateIDs []uint          `json:"affected_template_ids"`
+	Applied             bool            `json:"applied"`
+}
+
+// MergeCustomExercise re-points every template and session use of a custom exercise to
+// another exercise and soft-deletes the source. With dryRun set nothing is written and
+// only the preview is returned.
+func (es *ExerciseService) MergeCustomExercise(userID, sourceID, targetID uint, dryRun bool) (*ExerciseMergePreview, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSameExercise
+	}
+
+	preview := &ExerciseMergePreview{}
+
+	err := es.db.Transaction(func(tx *gorm.DB) error {
+		// Source must be a custom exercise owned by the user
+		err := tx.Where("id = ? AND created_by_user_id = ? AND is_custom = ?", sourceID, userID, true).First(&preview.SourceExercise).Error
+		if err != nil {
+			return err
+		}
+
+		// Target can be a built-in exercise or one of the user's own custom exercises
+		err = tx.Where("id = ? AND (is_custom = ? OR is_custom IS NULL OR created_by_user_id = ?)", targetID, false, userID).First(&preview.TargetExercise).Error
+		if err != nil {
+			return err
+		}
+
+		// Templates that already contain the target would end up with the exercise twice
+		conflicts := tx.Model(&models.TemplateExercise{}).
+			Where("exercise_id = ? AND template_id IN (?)", sourceID,
+				tx.Model(&models.TemplateExercise{}).Select("template_id").Where("exercise_id = ?", targetID))
+
+		err = conflicts.Session(&gorm.Session{}).Count(&preview.TemplateConflicts).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.TemplateExercise{}).Where("exercise_id = ?", sourceID).Count(&preview.TemplateExercises).Error
+		if err != nil {
+			return err
+		}
+		preview.TemplateExercises -= preview.TemplateConflicts
+
+		err = tx.Model(&models.TemplateExercise{}).Where("exercise_id = ?", sourceID).
+			Distinct("template_id").Pluck("template_id", &preview.AffectedTemplateIDs).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.SessionExercise{}).Where("exercise_id = ?", sourceID).Count(&preview.SessionExercises).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.ExerciseMedia{}).Where("exercise_id = ?", sourceID).Count(&preview.Media).Error
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		// Keep the target's row in conflicting templates and drop the duplicate along
+		// with its set prescriptions
+		var dropped []models.TemplateExercise
+		err = conflicts.Session(&gorm.Session{}).Find(&dropped).Error
+		if err != nil {
+			return err
+		}
+
+		for _, templateExercise := range dropped {
+			err = tx.Where("template_exercise_id = ?", templateExercise.ID).Delete(&models.TemplateSet{}).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Delete(&templateExercise).Error; err != nil {
+				return err
+			}
+
+			if err := compactTemplateExerciseOrder(tx, templateExercise.TemplateID); err != nil {
+				return err
+			}
+			if templateExercise.GroupID != nil {
+				if err := dissolveUndersizedTemplateGroup(tx, *templateExercise.GroupID); err != nil {
+					return err
+				}
+			}
+			if err := refreshTemplateEstimate(tx, templateExercise.TemplateID); err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(&models.TemplateExercise{}).Where("exercise_id = ?", sourceID).Update("exercise_id", targetID).Error
+		if err != nil {
+			return err
+		}
+
+		// Users who performed the source exercise need their records rebuilt afterwards
+		var userIDs []uint
+		err = tx.Model(&models.SessionExercise{}).
+			Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id").
+			Where("session_exercises.exercise_id = ?", sourceID).
+			Distinct("workout_sessions.user_id").
+			Pluck("workout_sessions.user_id", &userIDs).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.SessionExercise{}).Where("exercise_id = ?", sourceID).Update("exercise_id", targetID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.ExerciseMedia{}).Where("exercise_id = ?", sourceID).Update("exercise_id", targetID).Error
+		if err != nil {
+			return err
+		}
+
+		// Records now belong to the target; replay them with the merged history
+		err = tx.Unscoped().Where("exercise_id = ?", sourceID).Delete(&models.PersonalRecord{}).Error
+		if err != nil {
+			return err
+		}
+
+		recordService := NewPersonalRecordService(tx)
+		for _, recordUserID := range userIDs {
+			if err := recordService.RecalculateRecords(recordUserID, targetID); err != nil {
+				return err
+			}
+		}
+
+		// Nothing references the source anymore, so it can be soft-deleted
+		err = tx.Delete(&preview.SourceExercise).Error
+		if err != nil {
+			return err
+		}
+
+		preview.Applied = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// ExerciseHistoryEntry summarises one workout session in which an exercise was performed
+type ExerciseHistoryEntry struct {
+	WorkoutID          uint                 `json:"workout_id"`
+	WorkoutName        string               `json:"workout_name"`
+	Date               time.Time            `json:"date"`
+	Notes              string               `json:"notes"`
+	Sets               []models.ExerciseSet `json:"sets"`
+	BestSet            *models.ExerciseSet  `json:"best_set"`
+	TotalSets          int                  `json:"total_sets"`
+	WarmupSets         int                  `json:"warmup_sets"`
+	TotalReps          int                  `json:"total_reps"`
+	TotalVolume        float64              `json:"total_volume"` // sum of weight × reps, in kg
+	EstimatedOneRepMax *float64             `json:"estimated_one_rep_max"`
+}
+
+// ExerciseHistorySeries holds the history as parallel arrays (oldest first) for charting
+type ExerciseHistorySeries struct {
+	Dates              []time.Time `json:"dates"`
+	BestWeight         []*float64  `json:"best_weight"`
+	TotalVolume        []float64   `json:"total_volume"`
+	TotalReps          []int       `json:"total_reps"`
+	EstimatedOneRepMax []*float64  `json:"estimated_one_rep_max"`
+}
+
+// ExerciseHistory is the full performance history of one exercise for a user
+type ExerciseHistory struct {
+	Exercise models.Exercise        `json:"exercise"`
+	Formula  string                 `json:"formula"` // one-rep-max formula used for the estimates
+	Entries  []ExerciseHistoryEntry `json:"entries"` // newest first
+	Series   ExerciseHistorySeries  `json:"series"`
+}
+
+// GetExerciseHistory returns every session in which the user performed an exercise,
+// optionally limited to a date range (YYYY-MM-DD). Warm-up sets are listed but left
+// out of the totals and best set unless includeWarmups is set.
+func (es *ExerciseService) GetExerciseHistory(userID, exerciseID uint, startDate, endDate string, includeWarmups bool) (*ExerciseHistory, error) {
+	formula, err := NewStrengthService(es.db).GetUserFormula(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.getExerciseHistory(userID, exerciseID, formula, startDate, endDate, includeWarmups)
+}
+
+// GetExerciseRestHistory measures the rest taken before an exercise's sets in the
+// user's latest sessions that logged it, per session and across them all. Rest is
+// measured within each whole workout, so it counts from whichever set came before.
+func (es *ExerciseService) GetExerciseRestHistory(userID, exerciseID uint, sessions int) (*ExerciseRestHistory, error) {
+	history := &ExerciseRestHistory{Sessions: []ExerciseRestSession{}}
+
+	// Built-in exercises and the user's own custom exercises are visible
+	err := es.db.Where("id = ? AND (is_custom = ? OR is_custom IS NULL OR created_by_user_id = ?)", exerciseID, false, userID).First(&history.Exercise).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var workoutIDs []uint
+	err = es.db.Model(&models.WorkoutSession{}).
+		Joins("JOIN session_exercises ON session_exercises.session_id = workout_sessions.id AND session_exercises.deleted_at IS NULL").
+		Joins("JOIN exercise_sets ON exercise_sets.session_exercise_id = session_exercises.id AND exercise_sets.deleted_at IS NULL").
+		Where("workout_sessions.user_id = ? AND session_exercises.exercise_id = ?", userID, exerciseID).
+		Group("workout_sessions.id, workout_sessions.started_at").
+		Order("workout_sessions.started_at DESC").
+		Limit(sessions).
+		Pluck("workout_sessions.id", &workoutIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(workoutIDs) == 0 {
+		return history, nil
+	}
+
+	var workouts []models.WorkoutSession
+	err = es.db.Where("id IN ?", workoutIDs).
+		Preload("Exercises.Sets").
+		Preload("Exercises.PlannedSets").
+		Preload("Pauses").
+		Order("started_at DESC").
+		Find(&workouts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var allIntervals []SetRest
+	for i := range workouts {
+		var exerciseIntervals []SetRest
+		for _, interval := range restIntervals(&workouts[i]) {
+			if interval.ExerciseID == exerciseID {
+				exerciseIntervals = append(exerciseIntervals, interval)
+			}
+		}
+		allIntervals = append(allIntervals, exerciseIntervals...)
+
+		history.Sessions = append(history.Sessions, ExerciseRestSession{
+			WorkoutID:   workouts[i].ID,
+			Date:        workouts[i].StartedAt,
+			RestSummary: summarizeRest(exerciseIntervals),
+		})
+	}
+	history.Summary = summarizeRest(allIntervals)
+
+	return history, nil
+}
+
+// getExerciseHistory builds the history using a specific one-rep-max formula
+func (es *ExerciseService) getExerciseHistory(userID, exerciseID uint, formula, startDate, endDate string, includeWarmups bool) (*ExerciseHistory, error) {
+	history := &ExerciseHistory{
+		Formula: formula,
+		Entries: []ExerciseHistoryEntry{},
+		Series: ExerciseHistorySeries{
+			Dates:              []time.Time{},
+			BestWeight:         []*float64{},
+			TotalVolume:        []float64{},
+			TotalReps:          []int{},
+			EstimatedOneRepMax: []*float64{},
+		},
+	}
+
+	// Built-in exercises and the user's own custom exercises are visible
+	err := es.db.Where("id = ? AND (is_custom = ? OR is_custom IS NULL OR created_by_user_id = ?)", exerciseID, false, userID).First(&history.Exercise).Error
+	if err != nil {
+		return nil, err
+	}
+
+	query := es.db.Model(&models.SessionExercise{}).
+		Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id AND workout_sessions.deleted_at IS NULL").
+		Where("workout_sessions.user_id = ? AND session_exercises.exercise_id = ?", userID, exerciseID)
+
+	// Apply date filters if provided
+	if startDate != "" {
+		query = query.Where("workout_sessions.started_at >= ?", startDate+" 00:00:00")
+	}
+	if endDate != "" {
+		query = query.Where("workout_sessions.started_at <= ?", endDate+" 23:59:59")
+	}
+
+	var sessionExercises []models.SessionExercise
+	err = query.Preload("Session").
+		Preload("Sets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_number ASC")
+		}).
+		Order("workout_sessions.started_at ASC, session_exercises.order_index ASC").
+		Find(&sessionExercises).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The same exercise can appear more than once in a session, so group by session
+	var entries []*ExerciseHistoryEntry
+	entriesBySession := make(map[uint]*ExerciseHistoryEntry)
+	for _, sessionExercise := range sessionExercises {
+		if len(sessionExercise.Sets) == 0 {
+			continue
+		}
+
+		entry, ok := entriesBySession[sessionExercise.SessionID]
+		if !ok {
+			entry = &ExerciseHistoryEntry{
+				WorkoutID:   sessionExercise.SessionID,
+				WorkoutName: sessionExercise.Session.Name,
+				Date:        sessionExercise.Session.StartedAt,
+			}
+			entriesBySession[sessionExercise.SessionID] = entry
+			entries = append(entries, entry)
+		}
+
+		if sessionExercise.Notes != "" {
+			if entry.Notes != "" {
+				entry.Notes += "\n"
+			}
+			entry.Notes += sessionExercise.Notes
+		}
+		entry.Sets = append(entry.Sets, sessionExercise.Sets...)
+	}
+
+	for _, entry := range entries {
+		for i, set := range entry.Sets {
+			if set.IsWarmup() {
+				entry.WarmupSets++
+				if !includeWarmups {
+					continue
+				}
+			}
+
+			entry.TotalSets++
+			entry.TotalReps += derefInt(set.Reps)
+			entry.TotalVolume += setVolume(set)
+
+			if entry.BestSet == nil || isBetterSet(set, *entry.BestSet, formula) {
+				entry.BestSet = &entry.Sets[i]
+			}
+		}
+		entry.TotalVolume = roundTo(entry.TotalVolume, 2)
+		if entry.BestSet != nil {
+			entry.EstimatedOneRepMax = setEstimatedOneRepMax(*entry.BestSet, formula)
+		}
+
+		var bestWeight *float64
+		for _, set := range entry.Sets {
+			if set.IsWarmup() && !includeWarmups {
+				continue
+			}
+			if set.Weight != nil && (bestWeight == nil || *set.Weight > *bestWeight) {
+				bestWeight = set.Weight
+			}
+		}
+
+		history.Series.Dates = append(history.Series.Dates, entry.Date)
+		history.Series.BestWeight = append(history.Series.BestWeight, bestWeight)
+		history.Series.TotalVolume = append(history.Series.TotalVolume, entry.TotalVolume)
+		history.Series.TotalReps = append(history.Series.TotalReps, entry.TotalReps)
+		history.Series.EstimatedOneRepMax = append(history.Series.EstimatedOneRepMax, entry.EstimatedOneRepMax)
+	}
+
+	// List newest first; the chart series stays oldest first
+	for i := len(entries) - 1; i >= 0; i-- {
+		history.Entries = append(history.Entries, *entries[i])
+	}
+
+	return history, nil
+}
+
 // GetMuscleGroups returns list of unique muscle groups
 func (es *ExerciseService) GetMuscleGroups() ([]string, error) {
 	var muscleGroups []string
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"onefit/backend/models"
+	"strings"
+	"time"
+)
+
+// icalLineLimit is the longest a content line may be, in octets, before it is folded
+const icalLineLimit = 75
+
+// buildICalendar writes scheduled events as an RFC 5545 calendar. Events with a start
+// time use floating times, which calendar apps show in the subscriber's own time zone.
+func buildICalendar(events []models.ScheduledEvent, now time.Time) string {
+	var b strings.Builder
+	line := func(content string) {
+		writeICalLine(&b, content)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//OneFit//Training Calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:OneFit")
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	line("X-PUBLISHED-TTL:PT1H")
+
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:scheduled-event-%d@onefit", event.ID))
+
+		stamp := event.UpdatedAt
+		if stamp.IsZero() {
+			stamp = now
+		}
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+
+		startTime, err := time.Parse("15:04", event.StartTime)
+		timed := event.StartTime != "" && err == nil
+		if timed {
+			line("DTSTART:" + event.StartDate.Format("20060102") + startTime.Format("T150405"))
+			if minutes := icalEventMinutes(event); minutes > 0 {
+				line(fmt.Sprintf("DURATION:PT%dM", minutes))
+			}
+		} else {
+			line("DTSTART;VALUE=DATE:" + event.StartDate.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + event.StartDate.AddDate(0, 0, 1).Format("20060102"))
+		}
+
+		if rule, err := parseRecurrence(event.Recurrence); err == nil && rule != nil {
+			line("RRULE:" + rule.String(timed))
+		}
+
+		line("SUMMARY:" + escapeICalText(event.Title))
+		if event.Notes != "" {
+			line("DESCRIPTION:" + escapeICalText(event.Notes))
+		}
+		line("CATEGORIES:" + strings.ToUpper(event.EventType))
+		if event.EventType == models.EventTypeRest {
+			line("TRANSP:TRANSPARENT")
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// icalEventMinutes is how long a timed event lasts: its own duration, or else an
+// hour for workouts and the target for fasts
+func icalEventMinutes(event models.ScheduledEvent) int {
+	if event.DurationMinutes > 0 {
+		return event.DurationMinutes
+	}
+	switch event.EventType {
+	case models.EventTypeWorkout:
+		return defaultWorkoutDuration
+	case models.EventTypeFast:
+		if event.TargetHours != nil {
+			return *event.TargetHours * 60
+		}
+	}
+	return 0
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// writeICalLine writes a content line ending in CRLF, folding it onto continuation
+// lines that start with a space once it passes the line limit. Lines are only split
+// between UTF-8 characters.
+func writeICalLine(b *strings.Builder, content string) {
+	limit := icalLineLimit
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		limit = icalLineLimit - 1 // the leading space counts
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
+
+// isUTF8Start reports whether a byte begins a UTF-8 character
+func isUTF8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"onefit/backend/models"
+	"onefit/backend/storage"
+	"onefit/backend/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MediaTypeImage = "image"
+	MediaTypeVideo = "video"
+
+	maxImageUploadBytes = 10 << 20  // 10 MB
+	maxVideoUploadBytes = 100 << 20 // 100 MB
+	thumbnailMaxSize    = 320       // longest thumbnail side in pixels
+	mediaURLExpiry      = time.Hour
+)
+
+var (
+	ErrStorageUnavailable = errors.New("media storage is not configured")
+	ErrUnsupportedMedia   = errors.New("unsupported media file")
+	ErrMediaTooLarge      = errors.New("media file is too large")
+)
+
+// allowedMediaTypes maps each media type to its accepted content types and file extensions
+var allowedMediaTypes = map[string]map[string]string{
+	MediaTypeImage: {
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+		"image/webp": ".webp",
+	},
+	MediaTypeVideo: {
+		"video/mp4":       ".mp4",
+		"video/webm":      ".webm",
+		"video/quicktime": ".mov",
+	},
+}
+
+type MediaService struct {
+	db *gorm.DB
+}
+
+func NewMediaService(db *gorm.DB) *MediaService {
+	return &MediaService{db: db}
+}
+
+// UploadExerciseMedia stores an image or video for one of the user's custom exercises
+func (ms *MediaService) UploadExerciseMedia(userID, exerciseID uint, mediaType string, file *multipart.FileHeader) (*models.ExerciseMedia, error) {
+	store := storage.GetStorage()
+	if store == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	allowed, ok := allowedMediaTypes[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown media type '%s'", ErrUnsupportedMedia, mediaType)
+	}
+
+	// Media can only be attached to the user's own custom exercises
+	var exercise models.Exercise
+	err := ms.db.Where("id = ? AND created_by_user_id = ? AND is_custom = ?", exerciseID, userID, true).First(&exercise).Error
+	if err != nil {
+		return nil, err
+	}
+
+	maxBytes := int64(maxImageUploadBytes)
+	if mediaType == MediaTypeVideo {
+		maxBytes = maxVideoUploadBytes
+	}
+	if file.Size > maxBytes {
+		return nil, fmt.Errorf("%w: limit is %d MB", ErrMediaTooLarge, maxBytes>>20)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: limit is %d MB", ErrMediaTooLarge, maxBytes>>20)
+	}
+
+	// Trust the file contents over the client-supplied header, falling back to the
+	// header only for containers the sniffer does not recognise (e.g. QuickTime)
+	contentType := http.DetectContentType(data)
+	if _, ok := allowed[contentType]; !ok {
+		declared := strings.ToLower(strings.TrimSpace(file.Header.Get("Content-Type")))
+		if contentType != "application/octet-stream" || allowed[declared] == "" {
+			return nil, fmt.Errorf("%w: %s is not an accepted %s format", ErrUnsupportedMedia, contentType, mediaType)
+		}
+		contentType = declared
+	}
+
+	// Generate the thumbnail before uploading anything so a broken image is rejected cleanly
+	var thumbnail []byte
+	if mediaType == MediaTypeImage {
+		thumbnail, err = utils.GenerateThumbnail(data, thumbnailMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedMedia, err)
+		}
+	}
+
+	ctx := context.Background()
+	prefix := fmt.Sprintf("exercises/%d/%ss", exerciseID, mediaType)
+
+	media := models.ExerciseMedia{
+		ExerciseID:       exerciseID,
+		UserID:           userID,
+		MediaType:        mediaType,
+		ContentType:      contentType,
+		SizeBytes:        int64(len(data)),
+		OriginalFilename: file.Filename,
+		StorageKey:       storage.NewObjectKey(prefix, allowed[contentType]),
+	}
+
+	err = store.Put(ctx, media.StorageKey, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store media: %v", err)
+	}
+
+	if thumbnail != nil {
+		media.ThumbnailKey = storage.NewObjectKey(prefix+"/thumbnails", ".jpg")
+		err = store.Put(ctx, media.ThumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg")
+		if err != nil {
+			ms.deleteObjects(store, media.StorageKey)
+			return nil, fmt.Errorf("failed to store thumbnail: %v", err)
+		}
+	}
+
+	err = ms.db.Create(&media).Error
+	if err != nil {
+		// Don't leave orphaned files behind
+		ms.deleteObjects(store, media.StorageKey, media.ThumbnailKey)
+		return nil, err
+	}
+
+	if err := ms.signMediaURLs(store, &media); err != nil {
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// GetExerciseMedia returns the media attached to an exercise the user can see, with signed URLs
+func (ms *MediaService) GetExerciseMedia(userID, exerciseID uint) ([]models.ExerciseMedia, error) {
+	store := storage.GetStorage()
+	if store == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	// Built-in exercises and the user's own custom exercises are visible
+	var exercise models.Exercise
+	err := ms.db.Where("id = ? AND (is_custom = ? OR is_custom IS NULL OR created_by_user_id = ?)", exerciseID, false, userID).First(&exercise).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var media []models.ExerciseMedia
+	err = ms.db.Where("exercise_id = ?", exerciseID).Order("created_at ASC").Find(&media).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range media {
+		if err := ms.signMediaURLs(store, &media[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// DeleteExerciseMedia removes an attachment and its stored files
+func (ms *MediaService) DeleteExerciseMedia(userID, exerciseID, mediaID uint) error {
+	store := storage.GetStorage()
+	if store == nil {
+		return ErrStorageUnavailable
+	}
+
+	var media models.ExerciseMedia
+	err := ms.db.Where("id = ? AND exercise_id = ? AND user_id = ?", mediaID, exerciseID, userID).First(&media).Error
+	if err != nil {
+		return err
+	}
+
+	err = ms.db.Delete(&media).Error
+	if err != nil {
+		return err
+	}
+
+	ms.deleteObjects(store, media.StorageKey, media.ThumbnailKey)
+	return nil
+}
+
+// signMediaURLs fills in the time-limited download URLs for a media row
+func (ms *MediaService) signMediaURLs(store storage.BlobStorage, media *models.ExerciseMedia) error {
+	url, err := store.SignedURL(media.StorageKey, mediaURLExpiry)
+	if err != nil {
+		return err
+	}
+	media.URL = url
+
+	if media.ThumbnailKey != "" {
+		thumbnailURL, err := store.SignedURL(media.ThumbnailKey, mediaURLExpiry)
+		if err != nil {
+			return err
+		}
+		media.ThumbnailURL = thumbnailURL
+	}
+
+	return nil
+}
+
+// deleteObjects removes stored files on a best-effort basis
+func (ms *MediaService) deleteObjects(store storage.BlobStorage, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("Warning: failed to delete media object %s: %v", key, err)
+		}
+	}
+}
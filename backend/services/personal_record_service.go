@@ -0,0 +1,285 @@
+package services
+
+import (
+	"onefit/backend/models"
+
+	"gorm.io/gorm"
+)
+
+type PersonalRecordService struct {
+	db *gorm.DB
+}
+
+func NewPersonalRecordService(db *gorm.DB) *PersonalRecordService {
+	return &PersonalRecordService{db: db}
+}
+
+// recordValue is one measurable quantity of a set that can become a record
+type recordValue struct {
+	recordType string
+	value      float64
+	atWeight   *float64
+}
+
+// recordKey identifies an independent record line; reps records are tracked per weight
+type recordKey struct {
+	recordType string
+	atWeight   float64
+}
+
+// setRecordValues lists every record-eligible value a set provides
+func setRecordValues(set models.ExerciseSet, formula string) []recordValue {
+	var values []recordValue
+
+	if set.Weight != nil && *set.Weight > 0 {
+		values = append(values, recordValue{recordType: models.RecordHeaviestWeight, value: *set.Weight})
+	}
+
+	if set.Reps != nil && *set.Reps > 0 {
+		atWeight := 0.0 // bodyweight sets compete with each other
+		if set.Weight != nil {
+			atWeight = *set.Weight
+		}
+		values = append(values, recordValue{recordType: models.RecordMostRepsAtWeight, value: float64(*set.Reps), atWeight: &atWeight})
+	}
+
+	if estimate := setEstimatedOneRepMax(set, formula); estimate != nil {
+		values = append(values, recordValue{recordType: models.RecordBestOneRepMax, value: *estimate})
+	}
+
+	if volume := setVolume(set); volume > 0 {
+		values = append(values, recordValue{recordType: models.RecordBestSetVolume, value: roundTo(volume, 2)})
+	}
+
+	if set.DurationSeconds != nil && *set.DurationSeconds > 0 {
+		values = append(values, recordValue{recordType: models.RecordLongestDuration, value: float64(*set.DurationSeconds)})
+	}
+
+	if set.DistanceMeters != nil && *set.DistanceMeters > 0 {
+		values = append(values, recordValue{recordType: models.RecordLongestDistance, value: *set.DistanceMeters})
+	}
+
+	return values
+}
+
+// RecalculateRecords rebuilds the record history of one exercise for a user from the
+// logged sets. Replaying every set in order keeps records correct after edits and deletes.
+func (ps *PersonalRecordService) RecalculateRecords(userID, exerciseID uint) error {
+	// Estimated 1RM records follow the user's preferred formula
+	formula, err := NewStrengthService(ps.db).GetUserFormula(userID)
+	if err != nil {
+		return err
+	}
+
+	// Session exercises of live workouts that use this exercise
+	var sessionExercises []models.SessionExercise
+	err = ps.db.Model(&models.SessionExercise{}).
+		Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id AND workout_sessions.deleted_at IS NULL").
+		Where("workout_sessions.user_id = ? AND session_exercises.exercise_id = ?", userID, exerciseID).
+		Find(&sessionExercises).Error
+	if err != nil {
+		return err
+	}
+
+	sessionIDs := make(map[uint]uint, len(sessionExercises))
+	sessionExerciseIDs := make([]uint, 0, len(sessionExercises))
+	for _, sessionExercise := range sessionExercises {
+		sessionIDs[sessionExercise.ID] = sessionExercise.SessionID
+		sessionExerciseIDs = append(sessionExerciseIDs, sessionExercise.ID)
+	}
+
+	var sets []models.ExerciseSet
+	if len(sessionExerciseIDs) > 0 {
+		err = ps.db.Where("session_exercise_id IN ?", sessionExerciseIDs).
+			Order("completed_at ASC, id ASC").
+			Find(&sets).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replay sets chronologically; a set is a record when it strictly beats the best so far.
+	// Warm-up sets never count.
+	var records []models.PersonalRecord
+	latest := make(map[recordKey]int)
+	for _, set := range sets {
+		if set.IsWarmup() {
+			continue
+		}
+
+		for _, candidate := range setRecordValues(set, formula) {
+			key := recordKey{recordType: candidate.recordType}
+			if candidate.atWeight != nil {
+				key.atWeight = *candidate.atWeight
+			}
+
+			var previousValue *float64
+			if index, ok := latest[key]; ok {
+				if candidate.value <= records[index].Value {
+					continue
+				}
+				previous := records[index].Value
+				previousValue = &previous
+			}
+
+			records = append(records, models.PersonalRecord{
+				UserID:        userID,
+				ExerciseID:    exerciseID,
+				ExerciseSetID: set.ID,
+				SessionID:     sessionIDs[set.SessionExerciseID],
+				RecordType:    candidate.recordType,
+				Value:         candidate.value,
+				PreviousValue: previousValue,
+				AtWeight:      candidate.atWeight,
+				AchievedAt:    set.CompletedAt,
+			})
+			latest[key] = len(records) - 1
+		}
+	}
+
+	for _, index := range latest {
+		records[index].IsCurrent = true
+	}
+
+	// Records are derived data, so the old rows are replaced outright
+	err = ps.db.Unscoped().Where("user_id = ? AND exercise_id = ?", userID, exerciseID).Delete(&models.PersonalRecord{}).Error
+	if err != nil {
+		return err
+	}
+
+	if len(records) > 0 {
+		if err := ps.db.CreateInBatches(&records, 100).Error; err != nil {
+			return err
+		}
+	}
+
+	// Summaries store how many records their workout set
+	return refreshRecordCounts(ps.db, userID, exerciseID)
+}
+
+// RecordSet checks a newly logged set against the records that currently stand and
+// adds the ones it beats, leaving the rest of the history untouched. A set logged
+// before a standing record was achieved changes the history, so it is replayed in full.
+func (ps *PersonalRecordService) RecordSet(userID, exerciseID, sessionID uint, set models.ExerciseSet) error {
+	if set.IsWarmup() {
+		return nil
+	}
+
+	var current []models.PersonalRecord
+	err := ps.db.Where("user_id = ? AND exercise_id = ? AND is_current = ?", userID, exerciseID, true).Find(&current).Error
+	if err != nil {
+		return err
+	}
+
+	standing := make(map[recordKey]models.PersonalRecord, len(current))
+	for _, record := range current {
+		if record.AchievedAt.After(set.CompletedAt) {
+			return ps.RecalculateRecords(userID, exerciseID)
+		}
+
+		key := recordKey{recordType: record.RecordType}
+		if record.AtWeight != nil {
+			key.atWeight = *record.AtWeight
+		}
+		standing[key] = record
+	}
+
+	formula, err := NewStrengthService(ps.db).GetUserFormula(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range setRecordValues(set, formula) {
+		key := recordKey{recordType: candidate.recordType}
+		if candidate.atWeight != nil {
+			key.atWeight = *candidate.atWeight
+		}
+
+		var previousValue *float64
+		if previous, ok := standing[key]; ok {
+			if candidate.value <= previous.Value {
+				continue
+			}
+			previousValue = &previous.Value
+
+			err := ps.db.Model(&models.PersonalRecord{}).Where("id = ?", previous.ID).Update("is_current", false).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		record := models.PersonalRecord{
+			UserID:        userID,
+			ExerciseID:    exerciseID,
+			ExerciseSetID: set.ID,
+			SessionID:     sessionID,
+			RecordType:    candidate.recordType,
+			Value:         candidate.value,
+			PreviousValue: previousValue,
+			AtWeight:      candidate.atWeight,
+			AchievedAt:    set.CompletedAt,
+			IsCurrent:     true,
+		}
+		if err := ps.db.Create(&record).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttachSetRecords fills in the records a set currently holds that beat an earlier best
+func (ps *PersonalRecordService) AttachSetRecords(set *models.ExerciseSet) error {
+	var records []models.PersonalRecord
+	err := ps.db.Where("exercise_set_id = ? AND is_current = ? AND previous_value IS NOT NULL", set.ID, true).
+		Order("record_type ASC").
+		Find(&records).Error
+	if err != nil {
+		return err
+	}
+
+	set.PersonalRecords = records
+	return nil
+}
+
+// GetPersonalRecords returns the user's records for an exercise. By default only the
+// records that still stand are returned; includeHistory adds every earlier record.
+func (ps *PersonalRecordService) GetPersonalRecords(userID, exerciseID uint, includeHistory bool) ([]models.PersonalRecord, error) {
+	// Built-in exercises and the user's own custom exercises are visible
+	var exercise models.Exercise
+	err := ps.db.Where("id = ? AND (is_custom = ? OR is_custom IS NULL OR created_by_user_id = ?)", exerciseID, false, userID).First(&exercise).Error
+	if err != nil {
+		return nil, err
+	}
+
+	query := ps.db.Where("user_id = ? AND exercise_id = ?", userID, exerciseID)
+	if !includeHistory {
+		query = query.Where("is_current = ?", true)
+	}
+
+	var records []models.PersonalRecord
+	err = query.Order("record_type ASC, at_weight ASC, achieved_at DESC").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// recalculateSessionRecords rebuilds records for every exercise used in a workout session
+func (ps *PersonalRecordService) recalculateSessionRecords(userID, sessionID uint) error {
+	var exerciseIDs []uint
+	err := ps.db.Unscoped().Model(&models.SessionExercise{}).
+		Where("session_id = ?", sessionID).
+		Distinct("exercise_id").
+		Pluck("exercise_id", &exerciseIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, exerciseID := range exerciseIDs {
+		if err := ps.RecalculateRecords(userID, exerciseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,542 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"onefit/backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidProgram        = errors.New("invalid program")
+	ErrProgramInUse          = errors.New("leave the program before changing its weeks")
+	ErrNotEnrolled           = errors.New("not enrolled in a program")
+	ErrProgramDayUnavailable = errors.New("program day not found in the program you are enrolled in")
+)
+
+const (
+	maxProgramWeeks    = 52
+	defaultLoadPercent = 100.0
+	maxLoadPercent     = 200.0
+)
+
+// What the user's program has planned for a day
+const (
+	ProgramStatusNotStarted = "not_started" // before the enrolment's start date
+	ProgramStatusWorkout    = "workout"
+	ProgramStatusRest       = "rest"
+	ProgramStatusFinished   = "finished" // past the last week
+)
+
+type ProgramService struct {
+	db *gorm.DB
+}
+
+func NewProgramService(db *gorm.DB) *ProgramService {
+	return &ProgramService{db: db}
+}
+
+// ProgramWeekInput describes a week of a program being created or replaced
+type ProgramWeekInput struct {
+	LoadPercent *float64 // defaults to 100
+	RepChange   int
+	Notes       string
+	Days        []ProgramDayInput
+}
+
+// ProgramDayInput schedules a template on a day of the week
+type ProgramDayInput struct {
+	DayNumber  int
+	TemplateID uint
+	Name       string
+}
+
+// ProgramToday is what the user's program has planned for a date
+type ProgramToday struct {
+	EnrollmentID uint               `json:"enrollment_id"`
+	ProgramID    uint               `json:"program_id"`
+	ProgramName  string             `json:"program_name"`
+	Date         string             `json:"date"` // YYYY-MM-DD
+	Status       string             `json:"status"`
+	WeekNumber   int                `json:"week_number,omitempty"`
+	DayNumber    int                `json:"day_number,omitempty"`
+	LoadPercent  float64            `json:"load_percent,omitempty"`
+	RepChange    int                `json:"rep_change"`
+	Day          *models.ProgramDay `json:"day"` // nil unless a workout is planned
+}
+
+// programDate returns midnight UTC of the calendar date of t
+func programDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// orderByWeekNumber orders preloaded program weeks
+func orderByWeekNumber(db *gorm.DB) *gorm.DB {
+	return db.Order("week_number ASC")
+}
+
+// orderByDayNumber orders preloaded program days
+func orderByDayNumber(db *gorm.DB) *gorm.DB {
+	return db.Order("day_number ASC")
+}
+
+// preloadProgramWeeks loads a program's weeks and days in order, with their templates
+func preloadProgramWeeks(db *gorm.DB) *gorm.DB {
+	return db.Preload("Weeks", orderByWeekNumber).
+		Preload("Weeks.Days", orderByDayNumber).
+		Preload("Weeks.Days.Template")
+}
+
+// GetUserPrograms lists the user's programs, newest first
+func (ps *ProgramService) GetUserPrograms(userID uint) ([]models.Program, error) {
+	programs := []models.Program{}
+	err := preloadProgramWeeks(ps.db).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&programs).Error
+	return programs, err
+}
+
+// GetProgram returns one of the user's programs with its weeks and days
+func (ps *ProgramService) GetProgram(userID, programID uint) (*models.Program, error) {
+	var program models.Program
+	err := preloadProgramWeeks(ps.db).
+		Where("id = ? AND user_id = ?", programID, userID).
+		First(&program).Error
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+// CreateProgram creates a program; weeks are numbered in the order given
+func (ps *ProgramService) CreateProgram(userID uint, name, description string, weeks []ProgramWeekInput) (*models.Program, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidProgram)
+	}
+
+	programWeeks, err := ps.buildProgramWeeks(userID, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	program := models.Program{
+		UserID:      userID,
+		Name:        name,
+		Description: description,
+		Weeks:       programWeeks,
+	}
+	if err := ps.db.Create(&program).Error; err != nil {
+		return nil, err
+	}
+
+	return ps.GetProgram(userID, program.ID)
+}
+
+// UpdateProgram updates a program's details and, when weeks are given, replaces all
+// of its weeks. Weeks can't be replaced while the user is enrolled in the program,
+// since that would lose the days they have completed.
+func (ps *ProgramService) UpdateProgram(userID, programID uint, name, description *string, weeks *[]ProgramWeekInput) (*models.Program, error) {
+	var program models.Program
+	if err := ps.db.Where("id = ? AND user_id = ?", programID, userID).First(&program).Error; err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		if strings.TrimSpace(*name) == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidProgram)
+		}
+		program.Name = strings.TrimSpace(*name)
+	}
+	if description != nil {
+		program.Description = *description
+	}
+
+	var programWeeks []models.ProgramWeek
+	if weeks != nil {
+		enrollment, err := activeEnrollment(ps.db, userID)
+		if err != nil && !errors.Is(err, ErrNotEnrolled) {
+			return nil, err
+		}
+		if enrollment != nil && enrollment.ProgramID == program.ID {
+			return nil, ErrProgramInUse
+		}
+
+		programWeeks, err = ps.buildProgramWeeks(userID, *weeks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Weeks").Save(&program).Error; err != nil {
+			return err
+		}
+		if weeks == nil {
+			return nil
+		}
+
+		if err := deleteProgramWeeks(tx, program.ID); err != nil {
+			return err
+		}
+		for i := range programWeeks {
+			programWeeks[i].ProgramID = program.ID
+		}
+		if len(programWeeks) == 0 {
+			return nil
+		}
+		return tx.Create(&programWeeks).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.GetProgram(userID, program.ID)
+}
+
+// DeleteProgram deletes a program and ends the user's enrolment in it
+func (ps *ProgramService) DeleteProgram(userID, programID uint) error {
+	var program models.Program
+	if err := ps.db.Where("id = ? AND user_id = ?", programID, userID).First(&program).Error; err != nil {
+		return err
+	}
+
+	return ps.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ProgramEnrollment{}).
+			Where("program_id = ? AND ended_at IS NULL", program.ID).
+			Update("ended_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		if err := deleteProgramWeeks(tx, program.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&program).Error
+	})
+}
+
+// deleteProgramWeeks deletes the weeks of a program and their days
+func deleteProgramWeeks(tx *gorm.DB, programID uint) error {
+	err := tx.Where("week_id IN (?)", tx.Model(&models.ProgramWeek{}).Select("id").Where("program_id = ?", programID)).
+		Delete(&models.ProgramDay{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("program_id = ?", programID).Delete(&models.ProgramWeek{}).Error
+}
+
+// buildProgramWeeks validates weeks and the templates of their days, which must be
+// the user's own or public
+func (ps *ProgramService) buildProgramWeeks(userID uint, weeks []ProgramWeekInput) ([]models.ProgramWeek, error) {
+	if len(weeks) > maxProgramWeeks {
+		return nil, fmt.Errorf("%w: at most %d weeks are allowed", ErrInvalidProgram, maxProgramWeeks)
+	}
+
+	programWeeks := make([]models.ProgramWeek, 0, len(weeks))
+	for i, week := range weeks {
+		weekNumber := i + 1
+
+		loadPercent := defaultLoadPercent
+		if week.LoadPercent != nil {
+			loadPercent = *week.LoadPercent
+		}
+		if loadPercent <= 0 || loadPercent > maxLoadPercent {
+			return nil, fmt.Errorf("%w: week %d load_percent must be above 0 and at most %.0f", ErrInvalidProgram, weekNumber, maxLoadPercent)
+		}
+
+		days := make([]models.ProgramDay, 0, len(week.Days))
+		seen := make(map[int]bool)
+		for _, day := range week.Days {
+			if day.DayNumber < 1 || day.DayNumber > 7 {
+				return nil, fmt.Errorf("%w: week %d day_number must be between 1 and 7", ErrInvalidProgram, weekNumber)
+			}
+			if seen[day.DayNumber] {
+				return nil, fmt.Errorf("%w: week %d has more than one workout on day %d", ErrInvalidProgram, weekNumber, day.DayNumber)
+			}
+			seen[day.DayNumber] = true
+
+			var template models.WorkoutTemplate
+			err := ps.db.Where("id = ? AND (user_id = ? OR is_public = ?)", day.TemplateID, userID, true).First(&template).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: template %d not found or not accessible", ErrInvalidProgram, day.TemplateID)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			days = append(days, models.ProgramDay{
+				DayNumber:  day.DayNumber,
+				TemplateID: template.ID,
+				Name:       strings.TrimSpace(day.Name),
+			})
+		}
+
+		programWeeks = append(programWeeks, models.ProgramWeek{
+			WeekNumber:  weekNumber,
+			LoadPercent: loadPercent,
+			RepChange:   week.RepChange,
+			Notes:       week.Notes,
+			Days:        days,
+		})
+	}
+	return programWeeks, nil
+}
+
+// Enroll starts the user on a program from a start date, ending any program they
+// were following
+func (ps *ProgramService) Enroll(userID, programID uint, startDate time.Time) (*models.ProgramEnrollment, error) {
+	program, err := ps.GetProgram(userID, programID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasWorkouts := false
+	for _, week := range program.Weeks {
+		hasWorkouts = hasWorkouts || len(week.Days) > 0
+	}
+	if !hasWorkouts {
+		return nil, fmt.Errorf("%w: the program has no workouts scheduled", ErrInvalidProgram)
+	}
+
+	err = ps.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ProgramEnrollment{}).
+			Where("user_id = ? AND ended_at IS NULL", userID).
+			Update("ended_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.ProgramEnrollment{
+			UserID:    userID,
+			ProgramID: program.ID,
+			StartDate: programDate(startDate),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.GetEnrollment(userID)
+}
+
+// LeaveProgram ends the user's current enrolment
+func (ps *ProgramService) LeaveProgram(userID uint) error {
+	enrollment, err := activeEnrollment(ps.db, userID)
+	if err != nil {
+		return err
+	}
+	return ps.db.Model(enrollment).Update("ended_at", time.Now()).Error
+}
+
+// activeEnrollment returns the enrolment the user is following, or ErrNotEnrolled
+func activeEnrollment(db *gorm.DB, userID uint) (*models.ProgramEnrollment, error) {
+	var enrollments []models.ProgramEnrollment
+	err := db.Where("user_id = ? AND ended_at IS NULL", userID).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&enrollments).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(enrollments) == 0 {
+		return nil, ErrNotEnrolled
+	}
+	return &enrollments[0], nil
+}
+
+// GetEnrollment returns the user's current enrolment with its program, marking the
+// days completed so far
+func (ps *ProgramService) GetEnrollment(userID uint) (*models.ProgramEnrollment, error) {
+	enrollment, err := activeEnrollment(ps.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := preloadProgramWeeks(ps.db).First(&enrollment.Program, enrollment.ProgramID).Error; err != nil {
+		return nil, err
+	}
+
+	completed, err := completedProgramDays(ps.db, enrollment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range enrollment.Program.Weeks {
+		week := &enrollment.Program.Weeks[i]
+		for j := range week.Days {
+			day := &week.Days[j]
+			enrollment.TotalDays++
+			if sessionID, ok := completed[day.ID]; ok {
+				day.Completed = true
+				day.SessionID = &sessionID
+				enrollment.CompletedDays++
+			}
+		}
+	}
+
+	return enrollment, nil
+}
+
+// completedProgramDays maps each program day completed during an enrolment to the
+// last finished workout started from it
+func completedProgramDays(db *gorm.DB, enrollmentID uint) (map[uint]uint, error) {
+	var workouts []models.WorkoutSession
+	err := db.Select("id", "program_day_id").
+		Where("program_enrollment_id = ? AND program_day_id IS NOT NULL AND ended_at IS NOT NULL", enrollmentID).
+		Order("started_at ASC").
+		Find(&workouts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[uint]uint, len(workouts))
+	for _, workout := range workouts {
+		completed[*workout.ProgramDayID] = workout.ID
+	}
+	return completed, nil
+}
+
+// GetTodaysWorkout returns what the user's program has planned for a date. Days
+// count from the enrolment's start date, seven to a week.
+func (ps *ProgramService) GetTodaysWorkout(userID uint, date time.Time) (*ProgramToday, error) {
+	enrollment, err := ps.GetEnrollment(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	date = programDate(date)
+	today := &ProgramToday{
+		EnrollmentID: enrollment.ID,
+		ProgramID:    enrollment.ProgramID,
+		ProgramName:  enrollment.Program.Name,
+		Date:         date.Format("2006-01-02"),
+	}
+
+	daysSinceStart := int(date.Sub(enrollment.StartDate).Hours() / 24)
+	weeks := enrollment.Program.Weeks
+
+	switch {
+	case daysSinceStart < 0:
+		today.Status = ProgramStatusNotStarted
+	case daysSinceStart/7 >= len(weeks):
+		today.Status = ProgramStatusFinished
+	default:
+		week := weeks[daysSinceStart/7]
+		today.WeekNumber = week.WeekNumber
+		today.DayNumber = daysSinceStart%7 + 1
+		today.LoadPercent = week.LoadPercent
+		today.RepChange = week.RepChange
+
+		today.Status = ProgramStatusRest
+		for i := range week.Days {
+			if week.Days[i].DayNumber == today.DayNumber {
+				today.Day = &week.Days[i]
+				today.Status = ProgramStatusWorkout
+			}
+		}
+	}
+
+	return today, nil
+}
+
+// enrolledProgramDay looks up a day of the program the user is enrolled in, with
+// its week
+func enrolledProgramDay(db *gorm.DB, userID, dayID uint) (*models.ProgramDay, *models.ProgramEnrollment, error) {
+	enrollment, err := activeEnrollment(db, userID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return nil, nil, ErrProgramDayUnavailable
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var day models.ProgramDay
+	err = db.Joins("Week").
+		Preload("Template").
+		Where("program_days.id = ? AND Week.program_id = ?", dayID, enrollment.ProgramID).
+		First(&day).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrProgramDayUnavailable
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &day, enrollment, nil
+}
+
+// applyWeekProgression adjusts the planned sets of a workout started from a program
+// day by its week's load percentage and rep change, and notes it in the suggestion
+func applyWeekProgression(db *gorm.DB, userID, sessionID uint, week models.ProgramWeek) error {
+	if week.LoadPercent == defaultLoadPercent && week.RepChange == 0 {
+		return nil
+	}
+
+	increments, err := NewProgressionService(db).GetIncrements(userID)
+	if err != nil {
+		return err
+	}
+
+	var sessionExercises []models.SessionExercise
+	err = db.Where("session_id = ?", sessionID).
+		Preload("Exercise").
+		Preload("PlannedSets").
+		Find(&sessionExercises).Error
+	if err != nil {
+		return err
+	}
+
+	note := weekProgressionNote(week)
+	for _, sessionExercise := range sessionExercises {
+		increment := incrementFor(increments, sessionExercise.Exercise.Equipment)
+
+		for _, plannedSet := range sessionExercise.PlannedSets {
+			if plannedSet.TargetWeight != nil {
+				weight := roundToIncrement(*plannedSet.TargetWeight*week.LoadPercent/100, increment)
+				plannedSet.TargetWeight = &weight
+			}
+			if plannedSet.TargetReps != nil && plannedSet.SetType != models.SetTypeWarmup {
+				reps := *plannedSet.TargetReps + week.RepChange
+				if reps < 1 {
+					reps = 1
+				}
+				plannedSet.TargetReps = &reps
+			}
+			if err := db.Save(&plannedSet).Error; err != nil {
+				return err
+			}
+		}
+
+		suggestion := note
+		if sessionExercise.Suggestion != "" {
+			suggestion = sessionExercise.Suggestion + "; " + strings.ToLower(note[:1]) + note[1:]
+		}
+		err := db.Model(&models.SessionExercise{}).
+			Where("id = ?", sessionExercise.ID).
+			Update("suggestion", suggestion).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// weekProgressionNote describes a week's progression, e.g. "Week 3: 90% load, -2 reps"
+func weekProgressionNote(week models.ProgramWeek) string {
+	var changes []string
+	if week.LoadPercent != defaultLoadPercent {
+		changes = append(changes, fmt.Sprintf("%g%% load", week.LoadPercent))
+	}
+	if week.RepChange != 0 {
+		unit := "reps"
+		if week.RepChange == 1 || week.RepChange == -1 {
+			unit = "rep"
+		}
+		changes = append(changes, fmt.Sprintf("%+d %s", week.RepChange, unit))
+	}
+	return fmt.Sprintf("Week %d: %s", week.WeekNumber, strings.Join(changes, ", "))
+}
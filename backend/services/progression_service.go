@@ -0,0 +1,356 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"onefit/backend/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Default weight steps in kg per equipment type; bodyweight movements progress reps instead
+var defaultProgressionIncrements = map[string]float64{
+	"barbell":       2.5,
+	"dumbbell":      2,
+	"kettlebell":    4,
+	"machine":       5,
+	"cable":         2.5,
+	"smith machine": 2.5,
+	"ez bar":        2.5,
+	"bodyweight":    0,
+	"pull-up bar":   0,
+}
+
+// defaultProgressionIncrement applies to equipment without a default or override
+const defaultProgressionIncrement = 2.5
+
+var ErrInvalidIncrement = errors.New("invalid progression increment")
+
+type ProgressionService struct {
+	db *gorm.DB
+}
+
+func NewProgressionService(db *gorm.DB) *ProgressionService {
+	return &ProgressionService{db: db}
+}
+
+func normalizeEquipment(equipment string) string {
+	return strings.ToLower(strings.TrimSpace(equipment))
+}
+
+// GetIncrements returns the weight step per equipment type: the defaults merged
+// with the user's overrides
+func (ps *ProgressionService) GetIncrements(userID uint) (map[string]float64, error) {
+	increments := make(map[string]float64, len(defaultProgressionIncrements))
+	for equipment, increment := range defaultProgressionIncrements {
+		increments[equipment] = increment
+	}
+
+	var overrides []models.ProgressionIncrement
+	err := ps.db.Where("user_id = ?", userID).Find(&overrides).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, override := range overrides {
+		increments[override.Equipment] = override.Increment
+	}
+
+	return increments, nil
+}
+
+// SetIncrements stores the user's weight step for each given equipment type
+func (ps *ProgressionService) SetIncrements(userID uint, increments map[string]float64) (map[string]float64, error) {
+	for equipment, increment := range increments {
+		if normalizeEquipment(equipment) == "" {
+			return nil, fmt.Errorf("%w: equipment is required", ErrInvalidIncrement)
+		}
+		if increment < 0 || increment > 50 {
+			return nil, fmt.Errorf("%w: %s must be between 0 and 50 kg", ErrInvalidIncrement, equipment)
+		}
+	}
+
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		for equipment, increment := range increments {
+			equipment = normalizeEquipment(equipment)
+
+			var override models.ProgressionIncrement
+			err := tx.Where("user_id = ? AND equipment = ?", userID, equipment).First(&override).Error
+			if err == gorm.ErrRecordNotFound {
+				override = models.ProgressionIncrement{UserID: userID, Equipment: equipment}
+			} else if err != nil {
+				return err
+			}
+
+			override.Increment = increment
+			if err := tx.Save(&override).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.GetIncrements(userID)
+}
+
+// incrementFor looks up the weight step for an exercise's equipment
+func incrementFor(increments map[string]float64, equipment string) float64 {
+	if increment, ok := increments[normalizeEquipment(equipment)]; ok {
+		return increment
+	}
+	return defaultProgressionIncrement
+}
+
+// roundToIncrement rounds a weight to the nearest loadable step
+func roundToIncrement(weight, increment float64) float64 {
+	if increment <= 0 {
+		return roundTo(weight, 2)
+	}
+	return roundTo(math.Round(weight/increment)*increment, 2)
+}
+
+// lastPerformance returns the non-warm-up sets from the most recent session in which
+// the user logged the exercise
+func (ps *ProgressionService) lastPerformance(userID, exerciseID uint) ([]models.ExerciseSet, error) {
+	var sessionExercises []models.SessionExercise
+	err := ps.db.Model(&models.SessionExercise{}).
+		Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id AND workout_sessions.deleted_at IS NULL").
+		Where("workout_sessions.user_id = ? AND session_exercises.exercise_id = ?", userID, exerciseID).
+		Where("EXISTS (SELECT 1 FROM exercise_sets WHERE exercise_sets.session_exercise_id = session_exercises.id AND exercise_sets.deleted_at IS NULL AND (exercise_sets.set_type IS NULL OR exercise_sets.set_type <> ?))", models.SetTypeWarmup).
+		Preload("Sets", func(db *gorm.DB) *gorm.DB {
+			return db.Where("set_type IS NULL OR set_type <> ?", models.SetTypeWarmup).Order("set_number ASC")
+		}).
+		Order("workout_sessions.started_at DESC, session_exercises.id DESC").
+		Limit(1).
+		Find(&sessionExercises).Error
+	if err != nil || len(sessionExercises) == 0 {
+		return nil, err
+	}
+
+	return sessionExercises[0].Sets, nil
+}
+
+// currentOneRepMax returns the user's standing estimated 1RM record for an exercise
+func (ps *ProgressionService) currentOneRepMax(userID, exerciseID uint) (*float64, error) {
+	var records []models.PersonalRecord
+	err := ps.db.Where("user_id = ? AND exercise_id = ? AND record_type = ? AND is_current = ?", userID, exerciseID, models.RecordBestOneRepMax, true).
+		Limit(1).
+		Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+
+	return &records[0].Value, nil
+}
+
+// SuggestSets plans the sets for a template exercise. Rep targets use double
+// progression: reps climb within the range at the same weight, and once every set
+// reaches the top of the range the weight goes up by one increment and reps reset
+// to the bottom. Exercises with set prescriptions follow them instead. It returns
+// the planned sets and a short explanation.
+func (ps *ProgressionService) SuggestSets(userID uint, templateExercise models.TemplateExercise, increments map[string]float64) ([]models.PlannedSet, string, error) {
+	if len(templateExercise.Sets) > 0 {
+		return ps.planPrescribedSets(userID, templateExercise, increments)
+	}
+
+	lastSets, err := ps.lastPerformance(userID, templateExercise.ExerciseID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	setCount := templateExercise.TargetSets
+	if setCount <= 0 {
+		setCount = max(len(lastSets), 1)
+	}
+
+	increment := incrementFor(increments, templateExercise.Exercise.Equipment)
+	target := templateExercise.Target
+
+	// Heaviest weight used last time and the sets done with it
+	var workingWeight *float64
+	var workingSets []models.ExerciseSet
+	for _, set := range lastSets {
+		weight := derefFloat(set.Weight)
+		if workingWeight == nil || weight > *workingWeight {
+			workingWeight = &weight
+			workingSets = nil
+		}
+		if weight == *workingWeight {
+			workingSets = append(workingSets, set)
+		}
+	}
+	if workingWeight != nil && *workingWeight == 0 {
+		workingWeight = nil
+	}
+
+	weight := templateExercise.TargetWeight
+	var reps, duration *int
+	var distance *float64
+	var suggestion string
+
+	switch {
+	case target == nil:
+		if len(workingSets) > 0 {
+			weight = workingWeight
+			reps = workingSets[0].Reps
+			suggestion = "Repeat your last session"
+		}
+
+	case target.Type == models.TargetReps || target.Type == models.TargetRepRange:
+		minReps, maxReps := *target.MinReps, *target.MaxReps
+
+		oneRepMax, err := ps.currentOneRepMax(userID, templateExercise.ExerciseID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if target.PercentOneRepMax != nil && oneRepMax != nil {
+			load := roundToIncrement(*oneRepMax**target.PercentOneRepMax/100, increment)
+			weight = &load
+			reps = &minReps
+			suggestion = fmt.Sprintf("%g%% of your estimated 1RM of %g kg", *target.PercentOneRepMax, *oneRepMax)
+			break
+		}
+
+		if len(workingSets) == 0 {
+			reps = &minReps
+			suggestion = "No previous sets; start at the bottom of the range"
+			break
+		}
+
+		// Fewest reps done at the working weight decides whether the range is complete
+		lowestReps := derefInt(workingSets[0].Reps)
+		for _, set := range workingSets[1:] {
+			lowestReps = min(lowestReps, derefInt(set.Reps))
+		}
+		rangeComplete := len(workingSets) >= setCount && lowestReps >= maxReps
+
+		switch {
+		case rangeComplete && workingWeight != nil && increment > 0:
+			load := roundToIncrement(*workingWeight+increment, increment)
+			weight = &load
+			reps = &minReps
+			suggestion = fmt.Sprintf("All sets reached %d reps at %g kg last time; adding %g kg", maxReps, *workingWeight, increment)
+		case rangeComplete:
+			weight = workingWeight
+			reps = &maxReps
+			suggestion = "Top of the range reached last time; add load or move to a harder variation"
+		default:
+			nextReps := min(max(lowestReps+1, minReps), maxReps)
+			weight = workingWeight
+			reps = &nextReps
+			if workingWeight != nil {
+				suggestion = fmt.Sprintf("Build to %d reps on every set at %g kg before adding load", maxReps, *workingWeight)
+			} else {
+				suggestion = fmt.Sprintf("Build to %d reps on every set", maxReps)
+			}
+		}
+
+	case target.Type == models.TargetAMRAP:
+		if workingWeight != nil {
+			weight = workingWeight
+			suggestion = "Same weight as last time; beat your rep count"
+		}
+
+	case target.Type == models.TargetTime:
+		duration = target.DurationSeconds
+		if workingWeight != nil {
+			weight = workingWeight
+		}
+
+	case target.Type == models.TargetDistance:
+		distance = target.DistanceMeters
+		if workingWeight != nil {
+			weight = workingWeight
+		}
+	}
+
+	plannedSets := make([]models.PlannedSet, 0, setCount)
+	for setNumber := 1; setNumber <= setCount; setNumber++ {
+		plannedSets = append(plannedSets, models.PlannedSet{
+			SetNumber:             setNumber,
+			TargetReps:            reps,
+			TargetWeight:          weight,
+			TargetDurationSeconds: duration,
+			TargetDistanceMeters:  distance,
+			RestSeconds:           templateExercise.RestSeconds,
+			SetType:               models.SetTypeWorking,
+		})
+	}
+
+	return plannedSets, suggestion, nil
+}
+
+// planPrescribedSets turns set prescriptions into planned sets. Percentages of 1RM
+// are converted to weights from the current estimated 1RM record; without one the
+// weight is left for the user to choose.
+func (ps *ProgressionService) planPrescribedSets(userID uint, templateExercise models.TemplateExercise, increments map[string]float64) ([]models.PlannedSet, string, error) {
+	increment := incrementFor(increments, templateExercise.Exercise.Equipment)
+
+	var oneRepMax *float64
+	for _, prescription := range templateExercise.Sets {
+		if prescription.PercentOneRepMax != nil {
+			var err error
+			oneRepMax, err = ps.currentOneRepMax(userID, templateExercise.ExerciseID)
+			if err != nil {
+				return nil, "", err
+			}
+			break
+		}
+	}
+
+	missingOneRepMax := false
+	plannedSets := make([]models.PlannedSet, 0, len(templateExercise.Sets))
+	for _, prescription := range templateExercise.Sets {
+		plannedSet := models.PlannedSet{
+			SetNumber:    prescription.SetNumber,
+			TargetWeight: prescription.Weight,
+			TargetRPE:    prescription.RPE,
+			RestSeconds:  templateExercise.RestSeconds,
+			SetType:      prescription.SetType,
+		}
+		if prescription.RestSeconds != nil {
+			plannedSet.RestSeconds = *prescription.RestSeconds
+		}
+		if plannedSet.SetType == "" {
+			plannedSet.SetType = models.SetTypeWorking
+		}
+
+		if target := prescription.Target; target != nil {
+			switch target.Type {
+			case models.TargetReps, models.TargetRepRange:
+				plannedSet.TargetReps = target.MinReps
+			case models.TargetTime:
+				plannedSet.TargetDurationSeconds = target.DurationSeconds
+			case models.TargetDistance:
+				plannedSet.TargetDistanceMeters = target.DistanceMeters
+			}
+		}
+
+		if prescription.PercentOneRepMax != nil {
+			if oneRepMax != nil {
+				load := roundToIncrement(*oneRepMax**prescription.PercentOneRepMax/100, increment)
+				plannedSet.TargetWeight = &load
+			} else {
+				missingOneRepMax = true
+			}
+		}
+
+		plannedSets = append(plannedSets, plannedSet)
+	}
+
+	suggestion := fmt.Sprintf("Following %d prescribed sets", len(plannedSets))
+	switch {
+	case missingOneRepMax:
+		suggestion += "; no estimated 1RM yet, so choose the weight for percentage sets"
+	case oneRepMax != nil:
+		suggestion += fmt.Sprintf("; percentages use your estimated 1RM of %g kg", *oneRepMax)
+	}
+
+	return plannedSets, suggestion, nil
+}
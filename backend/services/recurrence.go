@@ -0,0 +1,220 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// Supported recurrence frequencies
+const (
+	recurrenceDaily   = "DAILY"
+	recurrenceWeekly  = "WEEKLY"
+	recurrenceMonthly = "MONTHLY"
+)
+
+// RRULE weekday codes in week order; weeks start on Monday as in RFC 5545
+var recurrenceWeekdays = []struct {
+	code    string
+	weekday time.Weekday
+}{
+	{"MO", time.Monday},
+	{"TU", time.Tuesday},
+	{"WE", time.Wednesday},
+	{"TH", time.Thursday},
+	{"FR", time.Friday},
+	{"SA", time.Saturday},
+	{"SU", time.Sunday},
+}
+
+// recurrenceRule is the subset of an RFC 5545 RRULE we support: FREQ (DAILY, WEEKLY
+// or MONTHLY), INTERVAL, BYDAY for weekly rules, and COUNT or UNTIL
+type recurrenceRule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday // weekly rules; defaults to the weekday of the start date
+	Count    int            // 0 for no limit
+	Until    *time.Time     // last date that can occur, midnight UTC
+}
+
+// parseRecurrence parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE,FR". An
+// empty rule means the event doesn't repeat and parses to nil.
+func parseRecurrence(rule string) (*recurrenceRule, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	if rule == "" {
+		return nil, nil
+	}
+
+	parsed := &recurrenceRule{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: '%s' is not NAME=VALUE", ErrInvalidRecurrence, part)
+		}
+
+		switch name {
+		case "FREQ":
+			if value != recurrenceDaily && value != recurrenceWeekly && value != recurrenceMonthly {
+				return nil, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+			}
+			parsed.Freq = value
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 || interval > 365 {
+				return nil, fmt.Errorf("%w: INTERVAL must be between 1 and 365", ErrInvalidRecurrence)
+			}
+			parsed.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, ok := recurrenceWeekday(code)
+				if !ok {
+					return nil, fmt.Errorf("%w: unknown BYDAY day '%s'", ErrInvalidRecurrence, code)
+				}
+				parsed.ByDay = append(parsed.ByDay, weekday)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRecurrence)
+			}
+			parsed.Count = count
+		case "UNTIL":
+			// Only the date matters; times such as 20240601T235959Z are accepted
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL must be a date such as 20240601", ErrInvalidRecurrence)
+			}
+			parsed.Until = &until
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, name)
+		}
+	}
+
+	if parsed.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if len(parsed.ByDay) > 0 && parsed.Freq != recurrenceWeekly {
+		return nil, fmt.Errorf("%w: BYDAY is only supported with FREQ=WEEKLY", ErrInvalidRecurrence)
+	}
+	if parsed.Count > 0 && parsed.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL can't be used together", ErrInvalidRecurrence)
+	}
+
+	sort.Slice(parsed.ByDay, func(i, j int) bool {
+		return weekdayIndex(parsed.ByDay[i]) < weekdayIndex(parsed.ByDay[j])
+	})
+	return parsed, nil
+}
+
+// recurrenceWeekday looks up an RRULE weekday code
+func recurrenceWeekday(code string) (time.Weekday, bool) {
+	for _, day := range recurrenceWeekdays {
+		if day.code == code {
+			return day.weekday, true
+		}
+	}
+	return 0, false
+}
+
+// weekdayIndex is the position of a weekday in a week starting on Monday
+func weekdayIndex(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// String formats the rule as an RRULE value. UNTIL is written as a date, or as the
+// end of that day for events with a start time, as RFC 5545 requires it to match
+// the type of the start.
+func (r *recurrenceRule) String(timed bool) string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, weekday := range r.ByDay {
+			codes = append(codes, recurrenceWeekdays[weekdayIndex(weekday)].code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		until := r.Until.Format("20060102")
+		if timed {
+			until += "T235959"
+		}
+		parts = append(parts, "UNTIL="+until)
+	}
+	return strings.Join(parts, ";")
+}
+
+// occurrencesBetween returns the dates from start on that the rule repeats on, limited
+// to from through to. All dates are midnight UTC. A nil rule occurs on start only.
+func (r *recurrenceRule) occurrencesBetween(start, from, to time.Time) []time.Time {
+	if r == nil {
+		if start.Before(from) || start.After(to) {
+			return nil
+		}
+		return []time.Time{start}
+	}
+
+	var dates []time.Time
+	occurred := 0
+	for period := 0; ; period++ {
+		periodStart, candidates := r.period(start, period)
+		if periodStart.After(to) {
+			return dates
+		}
+
+		for _, date := range candidates {
+			if date.Before(start) {
+				continue
+			}
+			if date.After(to) || (r.Until != nil && date.After(*r.Until)) {
+				return dates
+			}
+			occurred++
+			if r.Count > 0 && occurred > r.Count {
+				return dates
+			}
+			if !date.Before(from) {
+				dates = append(dates, date)
+			}
+		}
+	}
+}
+
+// period returns the first day of the nth period of the rule from start, and the
+// dates in it the rule occurs on
+func (r *recurrenceRule) period(start time.Time, n int) (time.Time, []time.Time) {
+	switch r.Freq {
+	case recurrenceWeekly:
+		monday := start.AddDate(0, 0, -weekdayIndex(start.Weekday())+n*r.Interval*7)
+		byDay := r.ByDay
+		if len(byDay) == 0 {
+			byDay = []time.Weekday{start.Weekday()}
+		}
+		dates := make([]time.Time, 0, len(byDay))
+		for _, weekday := range byDay {
+			dates = append(dates, monday.AddDate(0, 0, weekdayIndex(weekday)))
+		}
+		return monday, dates
+	case recurrenceMonthly:
+		firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		date := time.Date(firstOfMonth.Year(), firstOfMonth.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Month() != firstOfMonth.Month() {
+			// Months without the start's day are skipped, as in RFC 5545
+			return firstOfMonth, nil
+		}
+		return firstOfMonth, []time.Time{date}
+	default:
+		date := start.AddDate(0, 0, n*r.Interval)
+		return date, []time.Time{date}
+	}
+}
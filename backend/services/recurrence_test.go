@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"onefit/backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDate parses a YYYY-MM-DD date as midnight UTC
+func testDate(t *testing.T, value string) time.Time {
+	t.Helper()
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return date
+}
+
+// formatDates lists dates as YYYY-MM-DD, comma-separated
+func formatDates(dates []time.Time) string {
+	formatted := make([]string, 0, len(dates))
+	for _, date := range dates {
+		formatted = append(formatted, date.Format("2006-01-02"))
+	}
+	return strings.Join(formatted, ",")
+}
+
+func TestOccurrencesBetween(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		from  string
+		to    string
+		want  string
+	}{
+		{
+			name:  "no rule",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-01-31",
+			want: "2024-01-03",
+		},
+		{
+			name:  "no rule outside the range",
+			start: "2024-01-03", from: "2024-01-04", to: "2024-01-31",
+			want: "",
+		},
+		{
+			name:  "weekly on the start's weekday",
+			rule:  "FREQ=WEEKLY",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-01-24",
+			want: "2024-01-03,2024-01-10,2024-01-17,2024-01-24",
+		},
+		{
+			name:  "BYDAY day before the start weekday",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-01-15",
+			want: "2024-01-03,2024-01-08,2024-01-10,2024-01-15",
+		},
+		{
+			name:  "BYDAY days out of order",
+			rule:  "FREQ=WEEKLY;BYDAY=FR,TU",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-01-12",
+			want: "2024-01-02,2024-01-05,2024-01-09,2024-01-12",
+		},
+		{
+			name:  "COUNT with BYDAY",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-12-31",
+			want: "2024-01-01,2024-01-04,2024-01-08",
+		},
+		{
+			name:  "COUNT with BYDAY skips days before the start",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-12-31",
+			want: "2024-01-05,2024-01-08,2024-01-12",
+		},
+		{
+			name:  "COUNT counts occurrences before the range",
+			rule:  "FREQ=DAILY;COUNT=5",
+			start: "2024-01-01", from: "2024-01-04", to: "2024-12-31",
+			want: "2024-01-04,2024-01-05",
+		},
+		{
+			name:  "UNTIL includes its date",
+			rule:  "FREQ=DAILY;UNTIL=20240105",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-12-31",
+			want: "2024-01-01,2024-01-02,2024-01-03,2024-01-04,2024-01-05",
+		},
+		{
+			name:  "UNTIL with a time",
+			rule:  "FREQ=WEEKLY;UNTIL=20240117T235959Z",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-12-31",
+			want: "2024-01-03,2024-01-10,2024-01-17",
+		},
+		{
+			name:  "daily INTERVAL",
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-01-10",
+			want: "2024-01-01,2024-01-04,2024-01-07,2024-01-10",
+		},
+		{
+			name:  "weekly INTERVAL with BYDAY",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			start: "2024-01-02", from: "2024-01-01", to: "2024-01-31",
+			want: "2024-01-02,2024-01-04,2024-01-16,2024-01-18,2024-01-30",
+		},
+		{
+			name:  "monthly INTERVAL",
+			rule:  "FREQ=MONTHLY;INTERVAL=2",
+			start: "2024-01-15", from: "2024-01-01", to: "2024-07-31",
+			want: "2024-01-15,2024-03-15,2024-05-15,2024-07-15",
+		},
+		{
+			name:  "monthly on the 31st skips shorter months",
+			rule:  "FREQ=MONTHLY",
+			start: "2024-01-31", from: "2024-01-01", to: "2024-08-31",
+			want: "2024-01-31,2024-03-31,2024-05-31,2024-07-31,2024-08-31",
+		},
+		{
+			name:  "monthly on the 31st with COUNT",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: "2024-01-31", from: "2024-01-01", to: "2024-12-31",
+			want: "2024-01-31,2024-03-31,2024-05-31",
+		},
+		{
+			name:  "monthly on the 29th in a leap year",
+			rule:  "FREQ=MONTHLY;UNTIL=20240430",
+			start: "2024-01-29", from: "2024-01-01", to: "2024-12-31",
+			want: "2024-01-29,2024-02-29,2024-03-29,2024-04-29",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRecurrence(%q): %v", tt.rule, err)
+			}
+
+			dates := rule.occurrencesBetween(testDate(t, tt.start), testDate(t, tt.from), testDate(t, tt.to))
+			if got := formatDates(dates); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // formatted back as an all-day rule; empty for no rule
+	}{
+		{rule: "", want: ""},
+		{rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rule: "RRULE:freq=weekly;interval=1", want: "FREQ=WEEKLY"},
+		{rule: "FREQ=WEEKLY;BYDAY=FR,MO,WE", want: "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU;COUNT=4", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU;COUNT=4"},
+		{rule: "FREQ=MONTHLY;UNTIL=20241231T235959Z", want: "FREQ=MONTHLY;UNTIL=20241231"},
+	}
+
+	for _, tt := range tests {
+		rule, err := parseRecurrence(tt.rule)
+		if err != nil {
+			t.Errorf("parseRecurrence(%q): %v", tt.rule, err)
+			continue
+		}
+		got := ""
+		if rule != nil {
+			got = rule.String(false)
+		}
+		if got != tt.want {
+			t.Errorf("parseRecurrence(%q) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestParseRecurrenceRejects(t *testing.T) {
+	rules := []string{
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=DAILY;COUNT=3;UNTIL=20240105",
+		"FREQ=DAILY;BYMONTH=1",
+		"FREQ",
+	}
+
+	for _, rule := range rules {
+		if _, err := parseRecurrence(rule); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("parseRecurrence(%q): got %v, want ErrInvalidRecurrence", rule, err)
+		}
+	}
+}
+
+func TestApplyEventInputStartsOnFirstByDay(t *testing.T) {
+	tests := []struct {
+		name       string
+		recurrence string
+		start      string
+		want       string
+	}{
+		{name: "no rule", start: "2024-01-03", want: "2024-01-03"},
+		{name: "start on a BYDAY day", recurrence: "FREQ=WEEKLY;BYDAY=MO,WE", start: "2024-01-03", want: "2024-01-03"},
+		{name: "BYDAY day before the start weekday", recurrence: "FREQ=WEEKLY;BYDAY=MO", start: "2024-01-03", want: "2024-01-08"},
+		{name: "later BYDAY day in the same week", recurrence: "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3", start: "2024-01-03", want: "2024-01-05"},
+		{name: "INTERVAL keeps the first week", recurrence: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", start: "2024-01-03", want: "2024-01-15"},
+		{name: "monthly rules keep the start", recurrence: "FREQ=MONTHLY", start: "2024-01-31", want: "2024-01-31"},
+	}
+
+	cs := NewCalendarService(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event models.ScheduledEvent
+			err := cs.applyEventInput(&event, ScheduledEventInput{
+				EventType:  models.EventTypeRest,
+				StartDate:  testDate(t, tt.start),
+				Recurrence: tt.recurrence,
+			})
+			if err != nil {
+				t.Fatalf("applyEventInput: %v", err)
+			}
+			if got := event.StartDate.Format("2006-01-02"); got != tt.want {
+				t.Errorf("start date %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// The shifted start is the rule's first occurrence, so COUNT still holds
+	var event models.ScheduledEvent
+	err := cs.applyEventInput(&event, ScheduledEventInput{
+		EventType:  models.EventTypeRest,
+		StartDate:  testDate(t, "2024-01-03"),
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+	})
+	if err != nil {
+		t.Fatalf("applyEventInput: %v", err)
+	}
+	rule, err := parseRecurrence(event.Recurrence)
+	if err != nil {
+		t.Fatalf("parseRecurrence(%q): %v", event.Recurrence, err)
+	}
+	dates := rule.occurrencesBetween(event.StartDate, event.StartDate, testDate(t, "2024-12-31"))
+	if got, want := formatDates(dates), "2024-01-05,2024-01-08,2024-01-12"; got != want {
+		t.Errorf("occurrences %s, want %s", got, want)
+	}
+}
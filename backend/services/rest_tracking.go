@@ -0,0 +1,211 @@
+package services
+
+import (
+	"onefit/backend/models"
+	"sort"
+	"time"
+)
+
+// SetRest is the rest taken before one set, measured from the completion of the
+// previous set in the session
+type SetRest struct {
+	SetID             uint      `json:"set_id"`
+	SessionExerciseID uint      `json:"session_exercise_id"`
+	ExerciseID        uint      `json:"exercise_id"`
+	SetNumber         int       `json:"set_number"`
+	CompletedAt       time.Time `json:"completed_at"`
+	ActualSeconds     int       `json:"actual_seconds"`
+	PrescribedSeconds *int      `json:"prescribed_seconds"` // rest planned after the previous set
+	DifferenceSeconds *int      `json:"difference_seconds"` // actual minus prescribed
+}
+
+// RestSummary aggregates rest intervals
+type RestSummary struct {
+	Intervals                int      `json:"intervals"`
+	TotalSeconds             int      `json:"total_seconds"`
+	AverageSeconds           float64  `json:"average_seconds"`
+	MedianSeconds            float64  `json:"median_seconds"`
+	ShortestSeconds          int      `json:"shortest_seconds"`
+	LongestSeconds           int      `json:"longest_seconds"`
+	PrescribedIntervals      int      `json:"prescribed_intervals"` // intervals with a prescribed rest
+	AveragePrescribedSeconds *float64 `json:"average_prescribed_seconds"`
+	MedianPrescribedSeconds  *float64 `json:"median_prescribed_seconds"`
+	AverageDifferenceSeconds *float64 `json:"average_difference_seconds"`
+	OverPrescribed           int      `json:"over_prescribed"`  // rested longer than prescribed
+	UnderPrescribed          int      `json:"under_prescribed"` // rested shorter than prescribed
+}
+
+// ExerciseRestStats is the rest taken before the sets of one exercise in a session
+type ExerciseRestStats struct {
+	SessionExerciseID uint   `json:"session_exercise_id"`
+	ExerciseID        uint   `json:"exercise_id"`
+	ExerciseName      string `json:"exercise_name"`
+	RestSummary
+}
+
+// ExerciseRestSession is the rest taken before the sets of one exercise in one workout
+type ExerciseRestSession struct {
+	WorkoutID uint      `json:"workout_id"`
+	Date      time.Time `json:"date"`
+	RestSummary
+}
+
+// ExerciseRestHistory is the rest taken before the sets of one exercise across the
+// user's recent workouts
+type ExerciseRestHistory struct {
+	Exercise models.Exercise       `json:"exercise"`
+	Summary  RestSummary           `json:"summary"`  // every interval of the listed sessions
+	Sessions []ExerciseRestSession `json:"sessions"` // newest first
+}
+
+// WorkoutRestStats is the rest analysis of a workout session
+type WorkoutRestStats struct {
+	WorkoutID uint                `json:"workout_id"`
+	Summary   RestSummary         `json:"summary"`
+	Exercises []ExerciseRestStats `json:"exercises"`
+	Sets      []SetRest           `json:"sets"`
+}
+
+// plannedRestAfter returns the rest planned after a set: its planned slot's rest, or
+// for sets beyond the plan the rest of the exercise's last planned set
+func plannedRestAfter(setID uint, plannedSets []models.PlannedSet) *int {
+	var last *models.PlannedSet
+	for i := range plannedSets {
+		plannedSet := &plannedSets[i]
+		if plannedSet.ExerciseSetID != nil && *plannedSet.ExerciseSetID == setID {
+			return &plannedSet.RestSeconds
+		}
+		if last == nil || plannedSet.SetNumber > last.SetNumber {
+			last = plannedSet
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+	return &last.RestSeconds
+}
+
+// restIntervals measures the rest before every set of a workout from the sets'
+// completion times. Paused time and the time spent on a timed set are not counted
+// as rest, and drop sets are skipped since they follow their parent without rest.
+func restIntervals(workout *models.WorkoutSession) []SetRest {
+	type loggedSet struct {
+		set             models.ExerciseSet
+		sessionExercise *models.SessionExercise
+	}
+
+	var logged []loggedSet
+	for i := range workout.Exercises {
+		for _, set := range workout.Exercises[i].Sets {
+			logged = append(logged, loggedSet{set: set, sessionExercise: &workout.Exercises[i]})
+		}
+	}
+
+	sort.Slice(logged, func(i, j int) bool {
+		if logged[i].set.CompletedAt.Equal(logged[j].set.CompletedAt) {
+			return logged[i].set.ID < logged[j].set.ID
+		}
+		return logged[i].set.CompletedAt.Before(logged[j].set.CompletedAt)
+	})
+
+	intervals := []SetRest{}
+	for i := 1; i < len(logged); i++ {
+		current, previous := logged[i], logged[i-1]
+		if current.set.SetType == models.SetTypeDrop {
+			continue
+		}
+
+		gap := current.set.CompletedAt.Sub(previous.set.CompletedAt)
+		gap -= pausedBetween(workout.Pauses, previous.set.CompletedAt, current.set.CompletedAt)
+		gap -= time.Duration(derefInt(current.set.DurationSeconds)) * time.Second
+		actual := max(int(gap.Seconds()), 0)
+
+		interval := SetRest{
+			SetID:             current.set.ID,
+			SessionExerciseID: current.sessionExercise.ID,
+			ExerciseID:        current.sessionExercise.ExerciseID,
+			SetNumber:         current.set.SetNumber,
+			CompletedAt:       current.set.CompletedAt,
+			ActualSeconds:     actual,
+			PrescribedSeconds: plannedRestAfter(previous.set.ID, previous.sessionExercise.PlannedSets),
+		}
+		if interval.PrescribedSeconds != nil {
+			difference := actual - *interval.PrescribedSeconds
+			interval.DifferenceSeconds = &difference
+		}
+
+		intervals = append(intervals, interval)
+	}
+
+	return intervals
+}
+
+// summarizeRest aggregates rest intervals
+func summarizeRest(intervals []SetRest) RestSummary {
+	var summary RestSummary
+	var prescribedTotal, differenceTotal int
+	var actual, prescribed []int
+
+	for _, interval := range intervals {
+		if summary.Intervals == 0 || interval.ActualSeconds < summary.ShortestSeconds {
+			summary.ShortestSeconds = interval.ActualSeconds
+		}
+		summary.LongestSeconds = max(summary.LongestSeconds, interval.ActualSeconds)
+		summary.Intervals++
+		summary.TotalSeconds += interval.ActualSeconds
+		actual = append(actual, interval.ActualSeconds)
+
+		if interval.PrescribedSeconds == nil {
+			continue
+		}
+		summary.PrescribedIntervals++
+		prescribedTotal += *interval.PrescribedSeconds
+		prescribed = append(prescribed, *interval.PrescribedSeconds)
+		differenceTotal += *interval.DifferenceSeconds
+
+		switch {
+		case *interval.DifferenceSeconds > 0:
+			summary.OverPrescribed++
+		case *interval.DifferenceSeconds < 0:
+			summary.UnderPrescribed++
+		}
+	}
+
+	if summary.Intervals > 0 {
+		summary.AverageSeconds = roundTo(float64(summary.TotalSeconds)/float64(summary.Intervals), 1)
+		summary.MedianSeconds = medianSeconds(actual)
+	}
+	if summary.PrescribedIntervals > 0 {
+		averagePrescribed := roundTo(float64(prescribedTotal)/float64(summary.PrescribedIntervals), 1)
+		averageDifference := roundTo(float64(differenceTotal)/float64(summary.PrescribedIntervals), 1)
+		summary.AveragePrescribedSeconds = &averagePrescribed
+		summary.AverageDifferenceSeconds = &averageDifference
+
+		medianPrescribed := medianSeconds(prescribed)
+		summary.MedianPrescribedSeconds = &medianPrescribed
+	}
+
+	return summary
+}
+
+// medianSeconds returns the median of a non-empty list of durations
+func medianSeconds(seconds []int) float64 {
+	sort.Ints(seconds)
+
+	middle := len(seconds) / 2
+	if len(seconds)%2 == 1 {
+		return float64(seconds[middle])
+	}
+	return roundTo(float64(seconds[middle-1]+seconds[middle])/2, 1)
+}
+
+// restTimerEnd returns when the rest after a set ends, or nil without a positive rest
+func restTimerEnd(completedAt time.Time, restSeconds *int) *time.Time {
+	if restSeconds == nil || *restSeconds <= 0 {
+		return nil
+	}
+
+	endsAt := completedAt.Add(time.Duration(*restSeconds) * time.Second)
+	return &endsAt
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"onefit/backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRotation     = errors.New("invalid rotation")
+	ErrRotationUnavailable = errors.New("rotation not found")
+)
+
+const (
+	minRotationTemplates = 2
+	maxRotationTemplates = 20
+)
+
+type RotationService struct {
+	db *gorm.DB
+}
+
+func NewRotationService(db *gorm.DB) *RotationService {
+	return &RotationService{db: db}
+}
+
+// RotationNext is the template due next in a rotation and why
+type RotationNext struct {
+	RotationID     uint                    `json:"rotation_id"`
+	Position       int                     `json:"position"`
+	TemplateID     uint                    `json:"template_id"`
+	Template       *models.WorkoutTemplate `json:"template"`
+	Chosen         bool                    `json:"chosen"`           // set by skipping or swapping rather than by the cycle
+	LastSessionID  *uint                   `json:"last_session_id"`  // the most recently finished workout of the rotation
+	LastTemplateID *uint                   `json:"last_template_id"` // its template
+}
+
+// orderByPosition orders preloaded rotation templates
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// GetUserRotations lists the user's rotations, newest first
+func (rs *RotationService) GetUserRotations(userID uint) ([]models.TemplateRotation, error) {
+	rotations := []models.TemplateRotation{}
+	err := rs.db.Where("user_id = ?", userID).
+		Preload("Templates", orderByPosition).
+		Preload("Templates.Template").
+		Order("created_at DESC").
+		Find(&rotations).Error
+	return rotations, err
+}
+
+// GetRotation returns one of the user's rotations with its templates in order
+func (rs *RotationService) GetRotation(userID, rotationID uint) (*models.TemplateRotation, error) {
+	var rotation models.TemplateRotation
+	err := rs.db.Where("id = ? AND user_id = ?", rotationID, userID).
+		Preload("Templates", orderByPosition).
+		Preload("Templates.Template").
+		First(&rotation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rotation, nil
+}
+
+// CreateRotation creates a rotation cycling through templates in the order given
+func (rs *RotationService) CreateRotation(userID uint, name string, templateIDs []uint) (*models.TemplateRotation, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRotation)
+	}
+
+	templates, err := rs.buildRotationTemplates(userID, templateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation := models.TemplateRotation{
+		UserID:    userID,
+		Name:      name,
+		Templates: templates,
+	}
+	if err := rs.db.Create(&rotation).Error; err != nil {
+		return nil, err
+	}
+
+	return rs.GetRotation(userID, rotation.ID)
+}
+
+// UpdateRotation renames a rotation and, when template IDs are given, replaces its
+// templates. Replacing them drops a skipped or swapped next template.
+func (rs *RotationService) UpdateRotation(userID, rotationID uint, name *string, templateIDs *[]uint) (*models.TemplateRotation, error) {
+	var rotation models.TemplateRotation
+	if err := rs.db.Where("id = ? AND user_id = ?", rotationID, userID).First(&rotation).Error; err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		if strings.TrimSpace(*name) == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidRotation)
+		}
+		rotation.Name = strings.TrimSpace(*name)
+	}
+
+	var templates []models.RotationTemplate
+	if templateIDs != nil {
+		var err error
+		templates, err = rs.buildRotationTemplates(userID, *templateIDs)
+		if err != nil {
+			return nil, err
+		}
+		rotation.NextTemplateID = nil
+		rotation.NextChosenAt = nil
+	}
+
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Templates").Save(&rotation).Error; err != nil {
+			return err
+		}
+		if templateIDs == nil {
+			return nil
+		}
+
+		if err := tx.Where("rotation_id = ?", rotation.ID).Delete(&models.RotationTemplate{}).Error; err != nil {
+			return err
+		}
+		for i := range templates {
+			templates[i].RotationID = rotation.ID
+		}
+		return tx.Create(&templates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rs.GetRotation(userID, rotation.ID)
+}
+
+// DeleteRotation deletes a rotation; workouts started from it are kept
+func (rs *RotationService) DeleteRotation(userID, rotationID uint) error {
+	var rotation models.TemplateRotation
+	if err := rs.db.Where("id = ? AND user_id = ?", rotationID, userID).First(&rotation).Error; err != nil {
+		return err
+	}
+
+	return rs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rotation_id = ?", rotation.ID).Delete(&models.RotationTemplate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&rotation).Error
+	})
+}
+
+// buildRotationTemplates validates template IDs, which must be distinct and the
+// user's own or public, and positions them in order
+func (rs *RotationService) buildRotationTemplates(userID uint, templateIDs []uint) ([]models.RotationTemplate, error) {
+	if len(templateIDs) < minRotationTemplates || len(templateIDs) > maxRotationTemplates {
+		return nil, fmt.Errorf("%w: a rotation needs between %d and %d templates", ErrInvalidRotation, minRotationTemplates, maxRotationTemplates)
+	}
+
+	// Each template appears once, so the last one finished has a single place
+	if len(uniqueIDs(templateIDs)) != len(templateIDs) {
+		return nil, fmt.Errorf("%w: a template can appear only once in a rotation", ErrInvalidRotation)
+	}
+
+	var count int64
+	err := rs.db.Model(&models.WorkoutTemplate{}).
+		Where("id IN ? AND (user_id = ? OR is_public = ?)", templateIDs, userID, true).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if int(count) != len(templateIDs) {
+		return nil, fmt.Errorf("%w: templates not found or not accessible", ErrInvalidRotation)
+	}
+
+	templates := make([]models.RotationTemplate, 0, len(templateIDs))
+	for i, templateID := range templateIDs {
+		templates = append(templates, models.RotationTemplate{
+			TemplateID: templateID,
+			Position:   i + 1,
+		})
+	}
+	return templates, nil
+}
+
+// GetNextTemplate returns the template due next in a rotation
+func (rs *RotationService) GetNextTemplate(userID, rotationID uint) (*RotationNext, error) {
+	rotation, err := rs.GetRotation(userID, rotationID)
+	if err != nil {
+		return nil, err
+	}
+	return nextInRotation(rs.db, rotation)
+}
+
+// SkipNext moves a rotation on past its next template without doing it
+func (rs *RotationService) SkipNext(userID, rotationID uint) (*RotationNext, error) {
+	rotation, err := rs.GetRotation(userID, rotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := nextInRotation(rs.db, rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Positions are 1-based, so the template after the next one is at index Position
+	skipTo := rotation.Templates[next.Position%len(rotation.Templates)].TemplateID
+	return rs.chooseNext(rotation, skipTo)
+}
+
+// SwapNext makes another template of a rotation the next one; the cycle carries on
+// from it
+func (rs *RotationService) SwapNext(userID, rotationID, templateID uint) (*RotationNext, error) {
+	rotation, err := rs.GetRotation(userID, rotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rotationTemplate := range rotation.Templates {
+		if rotationTemplate.TemplateID == templateID {
+			return rs.chooseNext(rotation, templateID)
+		}
+	}
+	return nil, fmt.Errorf("%w: template %d is not in the rotation", ErrInvalidRotation, templateID)
+}
+
+// chooseNext stores the template chosen as next and returns the new next template
+func (rs *RotationService) chooseNext(rotation *models.TemplateRotation, templateID uint) (*RotationNext, error) {
+	now := time.Now()
+	rotation.NextTemplateID = &templateID
+	rotation.NextChosenAt = &now
+
+	err := rs.db.Model(rotation).Updates(map[string]interface{}{
+		"next_template_id": rotation.NextTemplateID,
+		"next_chosen_at":   rotation.NextChosenAt,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return nextInRotation(rs.db, rotation)
+}
+
+// nextInRotation works out the next template of a rotation loaded with its
+// templates. It follows the template of the user's most recently finished workout
+// from the rotation, however it was started, unless a template was chosen since.
+// With neither, the rotation starts from the top.
+func nextInRotation(db *gorm.DB, rotation *models.TemplateRotation) (*RotationNext, error) {
+	if len(rotation.Templates) == 0 {
+		return nil, fmt.Errorf("%w: the rotation has no templates", ErrInvalidRotation)
+	}
+
+	templateIDs := make([]uint, 0, len(rotation.Templates))
+	for _, rotationTemplate := range rotation.Templates {
+		templateIDs = append(templateIDs, rotationTemplate.TemplateID)
+	}
+
+	var lastWorkouts []models.WorkoutSession
+	err := db.Select("id", "template_id", "ended_at").
+		Where("user_id = ? AND ended_at IS NOT NULL AND template_id IN ?", rotation.UserID, templateIDs).
+		Order("ended_at DESC").
+		Limit(1).
+		Find(&lastWorkouts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	next := &RotationNext{RotationID: rotation.ID}
+	index := 0
+
+	var last *models.WorkoutSession
+	if len(lastWorkouts) > 0 {
+		last = &lastWorkouts[0]
+		next.LastSessionID = &last.ID
+		next.LastTemplateID = last.TemplateID
+	}
+
+	chosen := rotation.NextTemplateID != nil && rotation.NextChosenAt != nil &&
+		(last == nil || last.EndedAt.Before(*rotation.NextChosenAt))
+
+	for i, rotationTemplate := range rotation.Templates {
+		if chosen && rotationTemplate.TemplateID == *rotation.NextTemplateID {
+			index = i
+			next.Chosen = true
+			break
+		}
+		if !chosen && last != nil && rotationTemplate.TemplateID == *last.TemplateID {
+			index = (i + 1) % len(rotation.Templates)
+			break
+		}
+	}
+
+	rotationTemplate := rotation.Templates[index]
+	next.Position = rotationTemplate.Position
+	next.TemplateID = rotationTemplate.TemplateID
+	next.Template = rotationTemplate.Template
+	return next, nil
+}
+
+// rotationNextTemplateID returns the template due next in one of the user's rotations
+func rotationNextTemplateID(db *gorm.DB, userID, rotationID uint) (uint, error) {
+	next, err := NewRotationService(db).GetNextTemplate(userID, rotationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrRotationUnavailable
+	}
+	if err != nil {
+		return 0, err
+	}
+	return next.TemplateID, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"math"
+	"onefit/backend/models"
+)
+
+// setVolume returns weight × reps for a set, or 0 if either is missing
+func setVolume(set models.ExerciseSet) float64 {
+	if set.Weight == nil || set.Reps == nil {
+		return 0
+	}
+	return *set.Weight * float64(*set.Reps)
+}
+
+// setEstimatedOneRepMax returns the estimate for a weighted set, or nil if it can't be computed
+func setEstimatedOneRepMax(set models.ExerciseSet, formula string) *float64 {
+	if set.Weight == nil || set.Reps == nil {
+		return nil
+	}
+
+	estimate, ok := EstimateOneRepMax(formula, *set.Weight, *set.Reps, set.RPE)
+	if !ok {
+		return nil
+	}
+	return &estimate
+}
+
+// isBetterSet reports whether candidate beats current, comparing by estimated 1RM,
+// then weight, reps, duration and distance so every kind of exercise has a "best set"
+func isBetterSet(candidate, current models.ExerciseSet, formula string) bool {
+	metrics := []func(models.ExerciseSet) float64{
+		func(s models.ExerciseSet) float64 { return derefFloat(setEstimatedOneRepMax(s, formula)) },
+		func(s models.ExerciseSet) float64 { return derefFloat(s.Weight) },
+		func(s models.ExerciseSet) float64 { return float64(derefInt(s.Reps)) },
+		func(s models.ExerciseSet) float64 { return float64(derefInt(s.DurationSeconds)) },
+		func(s models.ExerciseSet) float64 { return derefFloat(s.DistanceMeters) },
+	}
+
+	for _, metric := range metrics {
+		a, b := metric(candidate), metric(current)
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+func derefFloat(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
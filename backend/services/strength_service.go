@@ -0,0 +1,330 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"onefit/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Supported one-rep-max formulas
+const (
+	FormulaEpley    = "epley"
+	FormulaBrzycki  = "brzycki"
+	FormulaLombardi = "lombardi"
+	FormulaRPE      = "rpe"
+
+	DefaultOneRepMaxFormula = FormulaEpley
+)
+
+var ErrUnknownFormula = errors.New("unknown one-rep-max formula")
+
+// OneRepMaxFormula describes a selectable estimation formula
+type OneRepMaxFormula struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Equation    string `json:"equation"`
+	Description string `json:"description"`
+}
+
+var oneRepMaxFormulas = []OneRepMaxFormula{
+	{
+		Key:         FormulaEpley,
+		Name:        "Epley",
+		Equation:    "weight × (1 + reps / 30)",
+		Description: "General purpose estimate, most accurate for sets of 3-10 reps.",
+	},
+	{
+		Key:         FormulaBrzycki,
+		Name:        "Brzycki",
+		Equation:    "weight × 36 / (37 - reps)",
+		Description: "Slightly more conservative than Epley for low rep sets; undefined at 37+ reps.",
+	},
+	{
+		Key:         FormulaLombardi,
+		Name:        "Lombardi",
+		Equation:    "weight × reps^0.10",
+		Description: "Grows slowly with reps, giving conservative estimates for high rep sets.",
+	},
+	{
+		Key:         FormulaRPE,
+		Name:        "RPE adjusted",
+		Equation:    "Epley with reps + (10 - RPE)",
+		Description: "Counts reps left in reserve from the set's RPE. Falls back to Epley when no RPE was logged.",
+	},
+}
+
+// OneRepMaxFormulas returns every supported formula
+func OneRepMaxFormulas() []OneRepMaxFormula {
+	return oneRepMaxFormulas
+}
+
+// IsValidOneRepMaxFormula reports whether key names a supported formula
+func IsValidOneRepMaxFormula(key string) bool {
+	for _, formula := range oneRepMaxFormulas {
+		if formula.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// EstimateOneRepMax applies a formula to a single set. It returns false when the set
+// can't produce an estimate (no load, no reps, or outside the formula's range).
+func EstimateOneRepMax(formula string, weight float64, reps int, rpe *int) (float64, bool) {
+	if weight <= 0 || reps <= 0 {
+		return 0, false
+	}
+
+	var estimate float64
+	switch formula {
+	case FormulaEpley, "":
+		estimate = epley(weight, reps)
+	case FormulaBrzycki:
+		if reps >= 37 {
+			return 0, false
+		}
+		estimate = weight * 36 / float64(37-reps)
+	case FormulaLombardi:
+		estimate = weight * math.Pow(float64(reps), 0.10)
+	case FormulaRPE:
+		effectiveReps := reps
+		if rpe != nil {
+			// Reps in reserve count as reps the lifter could have done
+			effectiveReps += 10 - min(max(*rpe, 1), 10)
+		}
+		estimate = epley(weight, effectiveReps)
+	default:
+		return 0, false
+	}
+
+	return roundTo(estimate, 2), true
+}
+
+// epley treats a single as the true max so every formula agrees on 1-rep sets
+func epley(weight float64, reps int) float64 {
+	if reps == 1 {
+		return weight
+	}
+	return weight * (1 + float64(reps)/30)
+}
+
+type StrengthService struct {
+	db *gorm.DB
+}
+
+func NewStrengthService(db *gorm.DB) *StrengthService {
+	return &StrengthService{db: db}
+}
+
+// GetUserFormula returns the user's preferred formula, falling back to the default
+func (ss *StrengthService) GetUserFormula(userID uint) (string, error) {
+	var user models.User
+	err := ss.db.Select("id", "one_rep_max_formula").First(&user, userID).Error
+	if err != nil {
+		return "", err
+	}
+
+	if user.OneRepMaxFormula == "" {
+		return DefaultOneRepMaxFormula, nil
+	}
+	return user.OneRepMaxFormula, nil
+}
+
+// resolveFormula uses the requested formula if given, otherwise the user's preference
+func (ss *StrengthService) resolveFormula(userID uint, formula string) (string, error) {
+	if formula == "" {
+		return ss.GetUserFormula(userID)
+	}
+	if !IsValidOneRepMaxFormula(formula) {
+		return "", fmt.Errorf("%w: %s", ErrUnknownFormula, formula)
+	}
+	return formula, nil
+}
+
+// SetUserFormula stores the user's preferred formula and rebuilds their e1RM records with it
+func (ss *StrengthService) SetUserFormula(userID uint, formula string) error {
+	if !IsValidOneRepMaxFormula(formula) {
+		return fmt.Errorf("%w: %s", ErrUnknownFormula, formula)
+	}
+
+	return ss.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Update("one_rep_max_formula", formula).Error
+		if err != nil {
+			return err
+		}
+
+		// Estimated 1RM records depend on the formula
+		var exerciseIDs []uint
+		err = tx.Model(&models.PersonalRecord{}).Where("user_id = ?", userID).Distinct("exercise_id").Pluck("exercise_id", &exerciseIDs).Error
+		if err != nil {
+			return err
+		}
+
+		recordService := NewPersonalRecordService(tx)
+		for _, exerciseID := range exerciseIDs {
+			if err := recordService.RecalculateRecords(userID, exerciseID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetOneRepMax is the estimate for one logged set
+type SetOneRepMax struct {
+	SetID              uint     `json:"set_id"`
+	SetNumber          int      `json:"set_number"`
+	Reps               *int     `json:"reps"`
+	Weight             *float64 `json:"weight"`
+	RPE                *int     `json:"rpe"`
+	SetType            string   `json:"set_type"`
+	EstimatedOneRepMax *float64 `json:"estimated_one_rep_max"`
+}
+
+// SessionExerciseOneRepMax is the estimate for one exercise within a workout
+type SessionExerciseOneRepMax struct {
+	SessionExerciseID  uint           `json:"session_exercise_id"`
+	ExerciseID         uint           `json:"exercise_id"`
+	ExerciseName       string         `json:"exercise_name"`
+	EstimatedOneRepMax *float64       `json:"estimated_one_rep_max"` // best non-warm-up set of the session
+	Sets               []SetOneRepMax `json:"sets"`
+}
+
+// WorkoutOneRepMax holds the per-set and per-exercise estimates for a workout
+type WorkoutOneRepMax struct {
+	WorkoutID uint                       `json:"workout_id"`
+	Formula   string                     `json:"formula"`
+	Exercises []SessionExerciseOneRepMax `json:"exercises"`
+}
+
+// GetWorkoutOneRepMax estimates the 1RM of every set in a workout
+func (ss *StrengthService) GetWorkoutOneRepMax(userID, workoutID uint, formula string) (*WorkoutOneRepMax, error) {
+	formula, err := ss.resolveFormula(userID, formula)
+	if err != nil {
+		return nil, err
+	}
+
+	workout, err := NewWorkoutService(ss.db).GetWorkoutWithDetails(userID, workoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WorkoutOneRepMax{
+		WorkoutID: workout.ID,
+		Formula:   formula,
+		Exercises: []SessionExerciseOneRepMax{},
+	}
+
+	for _, sessionExercise := range workout.Exercises {
+		exerciseResult := SessionExerciseOneRepMax{
+			SessionExerciseID: sessionExercise.ID,
+			ExerciseID:        sessionExercise.ExerciseID,
+			ExerciseName:      sessionExercise.Exercise.Name,
+			Sets:              []SetOneRepMax{},
+		}
+
+		for _, set := range sessionExercise.Sets {
+			estimate := setEstimatedOneRepMax(set, formula)
+			exerciseResult.Sets = append(exerciseResult.Sets, SetOneRepMax{
+				SetID:              set.ID,
+				SetNumber:          set.SetNumber,
+				Reps:               set.Reps,
+				Weight:             set.Weight,
+				RPE:                set.RPE,
+				SetType:            set.SetType,
+				EstimatedOneRepMax: estimate,
+			})
+
+			if estimate != nil && !set.IsWarmup() && (exerciseResult.EstimatedOneRepMax == nil || *estimate > *exerciseResult.EstimatedOneRepMax) {
+				exerciseResult.EstimatedOneRepMax = estimate
+			}
+		}
+
+		result.Exercises = append(result.Exercises, exerciseResult)
+	}
+
+	return result, nil
+}
+
+// OneRepMaxTrendPoint is the best estimate from one workout session
+type OneRepMaxTrendPoint struct {
+	Date               time.Time `json:"date"`
+	WorkoutID          uint      `json:"workout_id"`
+	SetID              uint      `json:"set_id"`
+	Weight             float64   `json:"weight"`
+	Reps               int       `json:"reps"`
+	EstimatedOneRepMax float64   `json:"estimated_one_rep_max"`
+}
+
+// OneRepMaxTrend is the e1RM progression of one exercise over time
+type OneRepMaxTrend struct {
+	ExerciseID  uint                  `json:"exercise_id"`
+	Formula     string                `json:"formula"`
+	Points      []OneRepMaxTrendPoint `json:"points"` // oldest first
+	Current     *float64              `json:"current"`
+	AllTimeBest *float64              `json:"all_time_best"` // over all sessions, not just the range
+	Change      *float64              `json:"change"`        // last point minus first point
+}
+
+// GetOneRepMaxTrend returns the best estimated 1RM per session for an exercise,
+// optionally limited to a date range (YYYY-MM-DD)
+func (ss *StrengthService) GetOneRepMaxTrend(userID, exerciseID uint, formula, startDate, endDate string) (*OneRepMaxTrend, error) {
+	formula, err := ss.resolveFormula(userID, formula)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := NewExerciseService(ss.db).getExerciseHistory(userID, exerciseID, formula, startDate, endDate, false)
+	if err != nil {
+		return nil, err
+	}
+
+	trend := &OneRepMaxTrend{
+		ExerciseID: exerciseID,
+		Formula:    formula,
+		Points:     []OneRepMaxTrendPoint{},
+	}
+
+	// History entries are newest first
+	for i := len(history.Entries) - 1; i >= 0; i-- {
+		entry := history.Entries[i]
+		if entry.EstimatedOneRepMax == nil {
+			continue
+		}
+
+		trend.Points = append(trend.Points, OneRepMaxTrendPoint{
+			Date:               entry.Date,
+			WorkoutID:          entry.WorkoutID,
+			SetID:              entry.BestSet.ID,
+			Weight:             derefFloat(entry.BestSet.Weight),
+			Reps:               derefInt(entry.BestSet.Reps),
+			EstimatedOneRepMax: *entry.EstimatedOneRepMax,
+		})
+	}
+
+	// The all-time best ignores the date range
+	if startDate != "" || endDate != "" {
+		history, err = NewExerciseService(ss.db).getExerciseHistory(userID, exerciseID, formula, "", "", false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range history.Entries {
+		if entry.EstimatedOneRepMax != nil && (trend.AllTimeBest == nil || *entry.EstimatedOneRepMax > *trend.AllTimeBest) {
+			trend.AllTimeBest = entry.EstimatedOneRepMax
+		}
+	}
+
+	if len(trend.Points) > 0 {
+		current := trend.Points[len(trend.Points)-1].EstimatedOneRepMax
+		change := roundTo(current-trend.Points[0].EstimatedOneRepMax, 2)
+		trend.Current = &current
+		trend.Change = &change
+	}
+
+	return trend, nil
+}
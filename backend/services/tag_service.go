@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"onefit/backend/models"
+	"onefit/backend/queries"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+const (
+	maxTagLength = 50
+	maxTags      = 20 // per workout or template
+)
+
+type TagService struct {
+	db *gorm.DB
+}
+
+func NewTagService(db *gorm.DB) *TagService {
+	return &TagService{db: db}
+}
+
+// TagUsage is a tag with how many of the user's workouts and templates carry it
+type TagUsage struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	WorkoutCount  int    `json:"workout_count"`
+	TemplateCount int    `json:"template_count"`
+}
+
+// GetUserTags lists the user's tags by name with their usage
+func (tgs *TagService) GetUserTags(userID uint) ([]TagUsage, error) {
+	tags := []TagUsage{}
+	err := tgs.db.Raw(queries.GetUserTagsWithUsage, userID).Scan(&tags).Error
+	return tags, err
+}
+
+// normalizeTags trims and lower-cases tag names, dropping blanks and duplicates,
+// and sorts them
+func normalizeTags(names []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+		if name == "" || seen[name] {
+			continue
+		}
+		if len(name) > maxTagLength {
+			return nil, fmt.Errorf("%w: '%s' is longer than %d characters", ErrInvalidTag, name, maxTagLength)
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidTag, maxTags)
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// resolveTags returns the user's tags with the given names, creating missing ones
+func resolveTags(tx *gorm.DB, userID uint, names []string) ([]models.Tag, error) {
+	names, err := normalizeTags(names)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		var tag models.Tag
+		if err := tx.Where(models.Tag{UserID: userID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagNamesOf returns the names of tags
+func tagNamesOf(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// replaceTags sets the tags of a workout or template, which must have its ID loaded
+func replaceTags(tx *gorm.DB, owner interface{}, tags []models.Tag) error {
+	if len(tags) == 0 {
+		return tx.Model(owner).Association("Tags").Clear()
+	}
+	return tx.Model(owner).Association("Tags").Replace(tags)
+}
+
+// orderByName orders preloaded tags
+func orderByName(db *gorm.DB) *gorm.DB {
+	return db.Order("name ASC")
+}
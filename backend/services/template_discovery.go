@@ -0,0 +1,523 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"onefit/backend/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidDiscoveryFilter = errors.New("invalid discovery filter")
+	ErrInvalidRating          = errors.New("rating must be between 1 and 5")
+	ErrOwnTemplate            = errors.New("this is your own template")
+)
+
+// Orders for discovered templates
+const (
+	DiscoverSortPopular = "popular" // saves and forks, then rating
+	DiscoverSortRating  = "rating"  // average rating, unrated last
+	DiscoverSortForks   = "forks"   // users who duplicated it
+	DiscoverSortNewest  = "newest"
+)
+
+const (
+	estimatedSetWorkSeconds = 40 // a set without a time target, for duration estimates
+	anonymousAuthorName     = "Anonymous"
+)
+
+// popularityExpr weighs a duplicate above a save, as it means the template was used
+const popularityExpr = "(COALESCE(saves.count, 0) + 2 * COALESCE(forks.count, 0))"
+
+// TemplateDiscoveryFilter narrows public templates; empty fields don't filter
+type TemplateDiscoveryFilter struct {
+	Search      string // matched against the name, description and tags
+	Category    string
+	MuscleGroup string // templates with an exercise working it
+	Equipment   string // templates with an exercise using it
+	MinMinutes  int    // estimated length
+	MaxMinutes  int
+	Sort        string // defaults to popular
+}
+
+// DiscoverTemplates searches everyone's public templates, the user's own included,
+// and fills in their author and stats. Filtering, sorting and paging happen in the
+// database on the stored duration estimates; details are loaded for the page only.
+func (ts *TemplateService) DiscoverTemplates(userID uint, limit, offset int, filter TemplateDiscoveryFilter) ([]models.WorkoutTemplate, int64, error) {
+	if filter.Sort == "" {
+		filter.Sort = DiscoverSortPopular
+	}
+	if filter.Sort != DiscoverSortPopular && filter.Sort != DiscoverSortRating && filter.Sort != DiscoverSortForks && filter.Sort != DiscoverSortNewest {
+		return nil, 0, fmt.Errorf("%w: sort must be popular, rating, forks or newest", ErrInvalidDiscoveryFilter)
+	}
+	if filter.MinMinutes < 0 || filter.MaxMinutes < 0 || (filter.MaxMinutes > 0 && filter.MinMinutes > filter.MaxMinutes) {
+		return nil, 0, fmt.Errorf("%w: duration range is invalid", ErrInvalidDiscoveryFilter)
+	}
+
+	if err := ts.backfillTemplateEstimates(); err != nil {
+		return nil, 0, err
+	}
+
+	query := ts.db.Model(&models.WorkoutTemplate{}).Where("workout_templates.is_public = ?", true)
+
+	if filter.Category != "" {
+		query = query.Where("workout_templates.category = ?", filter.Category)
+	}
+	if filter.MuscleGroup != "" {
+		query = query.Where("workout_templates.id IN (?)", ts.templatesWithExercises().
+			Where("exercises.muscle_groups LIKE ?", "%"+strings.ToLower(filter.MuscleGroup)+"%"))
+	}
+	if filter.Equipment != "" {
+		query = query.Where("workout_templates.id IN (?)", ts.templatesWithExercises().
+			Where("exercises.equipment LIKE ?", "%"+strings.ToLower(filter.Equipment)+"%"))
+	}
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		query = query.Where(ts.db.Where("workout_templates.name LIKE ?", pattern).
+			Or("workout_templates.description LIKE ?", pattern).
+			Or("workout_templates.id IN (?)", ts.db.Table("workout_template_tags").
+				Select("workout_template_tags.workout_template_id").
+				Joins("JOIN tags ON workout_template_tags.tag_id = tags.id AND tags.deleted_at IS NULL").
+				Where("tags.name LIKE ?", strings.ToLower(pattern))))
+	}
+	if filter.MinMinutes > 0 {
+		query = query.Where("workout_templates.estimated_minutes >= ?", filter.MinMinutes)
+	}
+	if filter.MaxMinutes > 0 {
+		query = query.Where("workout_templates.estimated_minutes <= ?", filter.MaxMinutes)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var pageIDs []uint
+	err := ts.sortDiscoveredTemplates(query, filter.Sort).
+		Limit(limit).
+		Offset(offset).
+		Pluck("workout_templates.id", &pageIDs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(pageIDs) == 0 {
+		return []models.WorkoutTemplate{}, total, nil
+	}
+
+	var loaded []models.WorkoutTemplate
+	err = preloadTemplateDetails(ts.db.Where("id IN ?", pageIDs)).Find(&loaded).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Put the page back in the sorted order
+	byID := make(map[uint]models.WorkoutTemplate, len(loaded))
+	for _, template := range loaded {
+		byID[template.ID] = template
+	}
+	templates := make([]models.WorkoutTemplate, 0, len(pageIDs))
+	for _, id := range pageIDs {
+		if template, ok := byID[id]; ok {
+			groupTemplateExercises(&template)
+			templates = append(templates, template)
+		}
+	}
+
+	if err := ts.fillTemplateStats(userID, templates); err != nil {
+		return nil, 0, err
+	}
+	return templates, total, nil
+}
+
+// sortDiscoveredTemplates orders the query by the chosen sort, joining the
+// rating, save and fork counts it needs, newest first among equals
+func (ts *TemplateService) sortDiscoveredTemplates(query *gorm.DB, order string) *gorm.DB {
+	const newest = "workout_templates.created_at DESC, workout_templates.id DESC"
+	if order == DiscoverSortNewest {
+		return query.Order(newest)
+	}
+
+	ratings := ts.db.Model(&models.TemplateRating{}).
+		Select("template_id, ROUND(AVG(rating), 1) AS average, COUNT(*) AS count").
+		Group("template_id")
+	saves := ts.db.Model(&models.TemplateSave{}).
+		Select("template_id, COUNT(*) AS count").
+		Group("template_id")
+	// Every user who duplicated a template counts, even if they deleted their copy since
+	forks := ts.db.Table("workout_templates AS forks").
+		Select("forks.forked_from_id AS template_id, COUNT(DISTINCT forks.user_id) AS count").
+		Joins("JOIN workout_templates AS originals ON originals.id = forks.forked_from_id").
+		Where("forks.user_id <> originals.user_id").
+		Group("forks.forked_from_id")
+
+	query = query.
+		Joins("LEFT JOIN (?) AS ratings ON ratings.template_id = workout_templates.id", ratings).
+		Joins("LEFT JOIN (?) AS saves ON saves.template_id = workout_templates.id", saves).
+		Joins("LEFT JOIN (?) AS forks ON forks.template_id = workout_templates.id", forks)
+
+	// Unrated templates sort below every rated one
+	switch order {
+	case DiscoverSortPopular:
+		query = query.Order(popularityExpr + " DESC").Order("COALESCE(ratings.average, -1) DESC")
+	case DiscoverSortRating:
+		query = query.Order("COALESCE(ratings.average, -1) DESC").Order("COALESCE(ratings.count, 0) DESC")
+	case DiscoverSortForks:
+		query = query.Order("COALESCE(forks.count, 0) DESC")
+	}
+	return query.Order(newest)
+}
+
+// refreshTemplateEstimate stores a template's estimated length after its exercises,
+// sets or groups change, so discovery can filter and sort on it
+func refreshTemplateEstimate(tx *gorm.DB, templateID uint) error {
+	var template models.WorkoutTemplate
+	err := tx.Preload("Exercises.Sets").Preload("Groups").First(&template, templateID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&template).UpdateColumn("estimated_minutes", estimateTemplateMinutes(&template)).Error
+}
+
+// backfillTemplateEstimates stores the estimate of public templates saved before
+// estimates were, or made public without one
+func (ts *TemplateService) backfillTemplateEstimates() error {
+	var templateIDs []uint
+	err := ts.db.Model(&models.WorkoutTemplate{}).
+		Where("is_public = ? AND estimated_minutes IS NULL", true).
+		Pluck("id", &templateIDs).Error
+	if err != nil || len(templateIDs) == 0 {
+		return err
+	}
+
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		for _, templateID := range templateIDs {
+			if err := refreshTemplateEstimate(tx, templateID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// templatesWithExercises selects the IDs of templates joined with their exercises
+func (ts *TemplateService) templatesWithExercises() *gorm.DB {
+	return ts.db.Model(&models.TemplateExercise{}).
+		Select("template_exercises.template_id").
+		Joins("JOIN exercises ON template_exercises.exercise_id = exercises.id")
+}
+
+// preloadTemplateDetails loads a template's exercises in order with their sets,
+// groups and tags
+func preloadTemplateDetails(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Exercises.Exercise").
+		Preload("Exercises.Sets", orderBySetNumber).
+		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Preload("Groups").
+		Preload("Tags", orderByName)
+}
+
+// fillTemplateStats sets the author and stats of templates, as seen by the user
+func (ts *TemplateService) fillTemplateStats(userID uint, templates []models.WorkoutTemplate) error {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	templateIDs := make([]uint, 0, len(templates))
+	authorIDs := make([]uint, 0, len(templates))
+	for _, template := range templates {
+		templateIDs = append(templateIDs, template.ID)
+		authorIDs = append(authorIDs, template.UserID)
+	}
+
+	var authors []models.User
+	err := ts.db.Select("id", "name").Where("id IN ?", uniqueIDs(authorIDs)).Find(&authors).Error
+	if err != nil {
+		return err
+	}
+	authorNames := make(map[uint]string, len(authors))
+	for _, author := range authors {
+		authorNames[author.ID] = strings.TrimSpace(author.Name)
+	}
+
+	var ratings []struct {
+		TemplateID uint
+		Average    float64
+		Count      int
+	}
+	err = ts.db.Model(&models.TemplateRating{}).
+		Select("template_id, AVG(rating) AS average, COUNT(*) AS count").
+		Where("template_id IN ?", templateIDs).
+		Group("template_id").
+		Scan(&ratings).Error
+	if err != nil {
+		return err
+	}
+
+	var saves []struct {
+		TemplateID uint
+		Count      int
+	}
+	err = ts.db.Model(&models.TemplateSave{}).
+		Select("template_id, COUNT(*) AS count").
+		Where("template_id IN ?", templateIDs).
+		Group("template_id").
+		Scan(&saves).Error
+	if err != nil {
+		return err
+	}
+
+	// Every user who duplicated a template counts, even if they deleted their copy since
+	var forks []struct {
+		TemplateID uint
+		Count      int
+	}
+	err = ts.db.Table("workout_templates AS forks").
+		Select("forks.forked_from_id AS template_id, COUNT(DISTINCT forks.user_id) AS count").
+		Joins("JOIN workout_templates AS originals ON originals.id = forks.forked_from_id").
+		Where("forks.forked_from_id IN ? AND forks.user_id <> originals.user_id", templateIDs).
+		Group("forks.forked_from_id").
+		Scan(&forks).Error
+	if err != nil {
+		return err
+	}
+
+	var userRatings []models.TemplateRating
+	err = ts.db.Where("user_id = ? AND template_id IN ?", userID, templateIDs).Find(&userRatings).Error
+	if err != nil {
+		return err
+	}
+
+	var userSaves []models.TemplateSave
+	err = ts.db.Where("user_id = ? AND template_id IN ?", userID, templateIDs).Find(&userSaves).Error
+	if err != nil {
+		return err
+	}
+
+	stats := make(map[uint]*models.TemplateStats, len(templates))
+	for i := range templates {
+		template := &templates[i]
+		name := authorNames[template.UserID]
+		if name == "" {
+			name = anonymousAuthorName
+		}
+		template.Author = &models.TemplateAuthor{UserID: template.UserID, Name: name}
+
+		muscleGroups, equipment := templateFocus(template)
+		template.Stats = &models.TemplateStats{
+			EstimatedMinutes: estimateTemplateMinutes(template),
+			MuscleGroups:     muscleGroups,
+			Equipment:        equipment,
+		}
+		stats[template.ID] = template.Stats
+	}
+	for _, rating := range ratings {
+		average := roundTo(rating.Average, 1)
+		stats[rating.TemplateID].AverageRating = &average
+		stats[rating.TemplateID].RatingCount = rating.Count
+	}
+	for _, save := range saves {
+		stats[save.TemplateID].SaveCount = save.Count
+	}
+	for _, fork := range forks {
+		stats[fork.TemplateID].ForkCount = fork.Count
+	}
+	for _, rating := range userRatings {
+		stats[rating.TemplateID].UserRating = &rating.Rating
+	}
+	for _, save := range userSaves {
+		stats[save.TemplateID].Saved = true
+	}
+	return nil
+}
+
+// templateFocus lists the muscle groups and equipment of a template's exercises,
+// in the order they first appear
+func templateFocus(template *models.WorkoutTemplate) ([]string, []string) {
+	muscleGroups, equipment := []string{}, []string{}
+	seen := make(map[string]bool)
+	add := func(list []string, kind, value string) []string {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[kind+value] {
+			return list
+		}
+		seen[kind+value] = true
+		return append(list, value)
+	}
+
+	for _, templateExercise := range template.Exercises {
+		for _, muscleGroup := range strings.Split(templateExercise.Exercise.MuscleGroups, ",") {
+			muscleGroups = add(muscleGroups, "muscle:", muscleGroup)
+		}
+		equipment = add(equipment, "equipment:", templateExercise.Exercise.Equipment)
+	}
+	return muscleGroups, equipment
+}
+
+// estimateTemplateMinutes estimates how long a template takes from its sets and
+// rest. Sets with a time target take that long and others a fixed time. Grouped
+// exercises are done once per round, with the group's rest in place of their own.
+func estimateTemplateMinutes(template *models.WorkoutTemplate) int {
+	workSeconds := func(target *models.Target) int {
+		if target != nil && target.DurationSeconds != nil {
+			return *target.DurationSeconds
+		}
+		return estimatedSetWorkSeconds
+	}
+
+	seconds := 0
+	groupExercises := make(map[uint]int)
+	for _, templateExercise := range template.Exercises {
+		if templateExercise.GroupID != nil {
+			groupExercises[*templateExercise.GroupID]++
+			continue
+		}
+
+		if len(templateExercise.Sets) > 0 {
+			for _, set := range templateExercise.Sets {
+				target := set.Target
+				if target == nil {
+					target = templateExercise.Target
+				}
+				rest := templateExercise.RestSeconds
+				if set.RestSeconds != nil {
+					rest = *set.RestSeconds
+				}
+				seconds += workSeconds(target) + rest
+			}
+			continue
+		}
+		seconds += max(templateExercise.TargetSets, 1) * (workSeconds(templateExercise.Target) + templateExercise.RestSeconds)
+	}
+
+	for _, group := range template.Groups {
+		count := groupExercises[group.ID]
+		if count == 0 {
+			continue
+		}
+		round := (count-1)*group.RestBetweenExercisesSeconds + group.RestAfterRoundSeconds
+		for _, templateExercise := range template.Exercises {
+			if templateExercise.GroupID != nil && *templateExercise.GroupID == group.ID {
+				round += workSeconds(templateExercise.Target)
+			}
+		}
+		seconds += max(group.Rounds, 1) * round
+	}
+
+	return (seconds + 59) / 60
+}
+
+// publicTemplate finds a public template the user can rate or save, which must
+// belong to someone else
+func (ts *TemplateService) publicTemplate(userID, templateID uint) (*models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	if err := ts.db.Where("id = ? AND is_public = ?", templateID, true).First(&template).Error; err != nil {
+		return nil, err
+	}
+	if template.UserID == userID {
+		return nil, ErrOwnTemplate
+	}
+	return &template, nil
+}
+
+// getDiscoveredTemplate returns a public template with its details, author and stats
+func (ts *TemplateService) getDiscoveredTemplate(userID, templateID uint) (*models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	err := preloadTemplateDetails(ts.db.Where("id = ? AND is_public = ?", templateID, true)).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	groupTemplateExercises(&template)
+
+	templates := []models.WorkoutTemplate{template}
+	if err := ts.fillTemplateStats(userID, templates); err != nil {
+		return nil, err
+	}
+	return &templates[0], nil
+}
+
+// RateTemplate sets the user's rating of someone else's public template,
+// replacing any earlier rating
+func (ts *TemplateService) RateTemplate(userID, templateID uint, rating int) (*models.WorkoutTemplate, error) {
+	if rating < 1 || rating > 5 {
+		return nil, ErrInvalidRating
+	}
+	if _, err := ts.publicTemplate(userID, templateID); err != nil {
+		return nil, err
+	}
+
+	var existing []models.TemplateRating
+	err := ts.db.Where("user_id = ? AND template_id = ?", userID, templateID).Limit(1).Find(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		err = ts.db.Create(&models.TemplateRating{UserID: userID, TemplateID: templateID, Rating: rating}).Error
+	} else {
+		err = ts.db.Model(&existing[0]).Update("rating", rating).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.getDiscoveredTemplate(userID, templateID)
+}
+
+// RemoveRating withdraws the user's rating of a template. Ratings and saves are
+// removed outright so the user can add them again.
+func (ts *TemplateService) RemoveRating(userID, templateID uint) error {
+	return ts.db.Unscoped().Where("user_id = ? AND template_id = ?", userID, templateID).Delete(&models.TemplateRating{}).Error
+}
+
+// SaveTemplate bookmarks someone else's public template; saving it again does nothing
+func (ts *TemplateService) SaveTemplate(userID, templateID uint) (*models.WorkoutTemplate, error) {
+	if _, err := ts.publicTemplate(userID, templateID); err != nil {
+		return nil, err
+	}
+
+	var existing []models.TemplateSave
+	err := ts.db.Where("user_id = ? AND template_id = ?", userID, templateID).Limit(1).Find(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		if err := ts.db.Create(&models.TemplateSave{UserID: userID, TemplateID: templateID}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return ts.getDiscoveredTemplate(userID, templateID)
+}
+
+// UnsaveTemplate removes a template from the user's saved templates
+func (ts *TemplateService) UnsaveTemplate(userID, templateID uint) error {
+	return ts.db.Unscoped().Where("user_id = ? AND template_id = ?", userID, templateID).Delete(&models.TemplateSave{}).Error
+}
+
+// GetSavedTemplates returns the templates the user saved, most recently saved
+// first. Templates made private since drop out.
+func (ts *TemplateService) GetSavedTemplates(userID uint) ([]models.WorkoutTemplate, error) {
+	var templates []models.WorkoutTemplate
+	query := ts.db.Model(&models.WorkoutTemplate{}).
+		Joins("JOIN template_saves ON template_saves.template_id = workout_templates.id AND template_saves.deleted_at IS NULL").
+		Where("template_saves.user_id = ? AND workout_templates.is_public = ?", userID, true)
+
+	err := preloadTemplateDetails(query).
+		Order("template_saves.created_at DESC").
+		Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		groupTemplateExercises(&templates[i])
+	}
+
+	if err := ts.fillTemplateStats(userID, templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"onefit/backend/models"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TemplateFromWorkoutOptions controls how logged sets become template targets
+type TemplateFromWorkoutOptions struct {
+	IncludeWarmups bool     // count warm-up sets towards the targets
+	PerSet         bool     // also prescribe every set as it was logged
+	WeightRounding *float64 // kg step for target weights; nil uses the equipment's progression increment, 0 keeps exact weights
+	RestRounding   int      // seconds step for rest; 0 keeps exact rest
+}
+
+// loggedExercise gathers the sets and rest of one exercise across a workout
+type loggedExercise struct {
+	exerciseID uint
+	equipment  string
+	groupID    *uint
+	sets       []models.ExerciseSet
+	restBefore map[uint]int // set ID -> rest taken before it, for all but the first set
+}
+
+// CreateTemplateFromWorkout turns a finished workout into a new template. Each
+// exercise's target sets, rep range, weight and rest are derived from the sets
+// logged and the rest taken between them. An exercise logged more than once is
+// merged, and exercises without sets are left out.
+func (ts *TemplateService) CreateTemplateFromWorkout(userID, workoutID uint, name, description, category string, isPublic bool, options TemplateFromWorkoutOptions) (*models.WorkoutTemplate, error) {
+	workout, err := NewWorkoutService(ts.db).GetWorkoutWithDetails(userID, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	if workout.EndedAt == nil {
+		return nil, ErrWorkoutNotEnded
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = workout.Name
+	}
+	name = strings.TrimSpace(name)
+	if err := ts.checkTemplateName(userID, name, 0); err != nil {
+		return nil, err
+	}
+
+	increments, err := NewProgressionService(ts.db).GetIncrements(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logged := collectLoggedExercises(workout, options.IncludeWarmups)
+
+	template := models.WorkoutTemplate{
+		UserID:      userID,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Category:    strings.TrimSpace(category),
+		IsPublic:    isPublic,
+		Tags:        workout.Tags,
+	}
+
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+
+		groupIDs, err := copySessionGroupsToTemplate(tx, template.ID, workout.Groups, logged)
+		if err != nil {
+			return err
+		}
+
+		for i, exercise := range logged {
+			weightStep := incrementFor(increments, exercise.equipment)
+			if options.WeightRounding != nil {
+				weightStep = *options.WeightRounding
+			}
+
+			templateExercise := deriveTemplateExercise(exercise, weightStep, options.RestRounding)
+			templateExercise.TemplateID = template.ID
+			templateExercise.OrderIndex = i + 1
+			if exercise.groupID != nil {
+				if groupID, ok := groupIDs[*exercise.groupID]; ok {
+					templateExercise.GroupID = &groupID
+				}
+			}
+			if options.PerSet {
+				templateExercise.Sets = deriveTemplateSets(exercise, templateExercise, weightStep, options.RestRounding)
+				templateExercise.TargetSets = len(templateExercise.Sets)
+			}
+
+			if err := tx.Create(&templateExercise).Error; err != nil {
+				return err
+			}
+		}
+		return refreshTemplateEstimate(tx, template.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.GetTemplateWithExercises(userID, template.ID)
+}
+
+// collectLoggedExercises groups a workout's counted sets by exercise, in workout
+// order, along with the rest taken before each set after an exercise's first
+func collectLoggedExercises(workout *models.WorkoutSession, includeWarmups bool) []*loggedExercise {
+	restBefore := make(map[uint]int)
+	for _, interval := range restIntervals(workout) {
+		restBefore[interval.SetID] = interval.ActualSeconds
+	}
+
+	var logged []*loggedExercise
+	byExerciseID := make(map[uint]*loggedExercise)
+	for _, sessionExercise := range workout.Exercises {
+		var sets []models.ExerciseSet
+		for _, set := range sessionExercise.Sets {
+			if includeWarmups || !set.IsWarmup() {
+				sets = append(sets, set)
+			}
+		}
+		if len(sets) == 0 {
+			continue
+		}
+
+		exercise, ok := byExerciseID[sessionExercise.ExerciseID]
+		if !ok {
+			exercise = &loggedExercise{
+				exerciseID: sessionExercise.ExerciseID,
+				equipment:  sessionExercise.Exercise.Equipment,
+				groupID:    sessionExercise.GroupID,
+				restBefore: make(map[uint]int),
+			}
+			byExerciseID[sessionExercise.ExerciseID] = exercise
+			logged = append(logged, exercise)
+		}
+		exercise.sets = append(exercise.sets, sets...)
+	}
+
+	for _, exercise := range logged {
+		sort.SliceStable(exercise.sets, func(i, j int) bool {
+			return exercise.sets[i].CompletedAt.Before(exercise.sets[j].CompletedAt)
+		})
+		for _, set := range exercise.sets[1:] {
+			if rest, ok := restBefore[set.ID]; ok {
+				exercise.restBefore[set.ID] = rest
+			}
+		}
+	}
+
+	return logged
+}
+
+// copySessionGroupsToTemplate copies the workout's supersets and circuits that
+// still have enough exercises, returning the template group for each session group
+func copySessionGroupsToTemplate(tx *gorm.DB, templateID uint, groups []models.SessionExerciseGroup, logged []*loggedExercise) (map[uint]uint, error) {
+	groupIDs := make(map[uint]uint)
+	for _, group := range groups {
+		members := 0
+		for _, exercise := range logged {
+			if exercise.groupID != nil && *exercise.groupID == group.ID {
+				members++
+			}
+		}
+		if validateGroup(group.GroupType, members, group.Rounds, group.RestBetweenExercisesSeconds, group.RestAfterRoundSeconds) != nil {
+			continue
+		}
+
+		templateGroup := models.TemplateExerciseGroup{
+			TemplateID:                  templateID,
+			GroupType:                   group.GroupType,
+			Name:                        group.Name,
+			Rounds:                      group.Rounds,
+			RestBetweenExercisesSeconds: group.RestBetweenExercisesSeconds,
+			RestAfterRoundSeconds:       group.RestAfterRoundSeconds,
+		}
+		if err := tx.Omit("Exercises").Create(&templateGroup).Error; err != nil {
+			return nil, err
+		}
+		groupIDs[group.ID] = templateGroup.ID
+	}
+
+	return groupIDs, nil
+}
+
+// deriveTemplateExercise sets the single-target fields from the logged sets: the
+// number of sets, the range of reps (or the longest time or distance), the heaviest
+// weight and the typical rest
+func deriveTemplateExercise(exercise *loggedExercise, weightStep float64, restStep int) models.TemplateExercise {
+	templateExercise := models.TemplateExercise{
+		ExerciseID: exercise.exerciseID,
+		TargetSets: len(exercise.sets),
+	}
+
+	var minReps, maxReps, longestDuration int
+	var farthestDistance, heaviestWeight float64
+	for _, set := range exercise.sets {
+		if set.Reps != nil && *set.Reps > 0 {
+			if minReps == 0 || *set.Reps < minReps {
+				minReps = *set.Reps
+			}
+			maxReps = max(maxReps, *set.Reps)
+		}
+		longestDuration = max(longestDuration, derefInt(set.DurationSeconds))
+		farthestDistance = math.Max(farthestDistance, derefFloat(set.DistanceMeters))
+		heaviestWeight = math.Max(heaviestWeight, derefFloat(set.Weight))
+	}
+
+	switch {
+	case minReps > 0 && minReps == maxReps:
+		templateExercise.TargetReps = strconv.Itoa(minReps)
+	case minReps > 0:
+		templateExercise.TargetReps = fmt.Sprintf("%d-%d", minReps, maxReps)
+	case longestDuration > 0:
+		templateExercise.TargetReps = fmt.Sprintf("%d sec", longestDuration)
+	case farthestDistance > 0:
+		templateExercise.TargetReps = fmt.Sprintf("%gm", roundTo(farthestDistance, 1))
+	}
+
+	if heaviestWeight > 0 {
+		weight := roundToIncrement(heaviestWeight, weightStep)
+		templateExercise.TargetWeight = &weight
+	}
+
+	var rests []int
+	for _, rest := range exercise.restBefore {
+		rests = append(rests, rest)
+	}
+	if len(rests) > 0 {
+		sort.Ints(rests)
+		templateExercise.RestSeconds = roundRest(rests[len(rests)/2], restStep)
+	}
+
+	return templateExercise
+}
+
+// deriveTemplateSets prescribes every logged set as it was done, with the rest
+// taken after it
+func deriveTemplateSets(exercise *loggedExercise, templateExercise models.TemplateExercise, weightStep float64, restStep int) []models.TemplateSet {
+	sets := make([]models.TemplateSet, 0, len(exercise.sets))
+	for i, set := range exercise.sets {
+		prescription := models.TemplateSet{
+			SetNumber:  i + 1,
+			TargetReps: templateExercise.TargetReps,
+			SetType:    set.SetType,
+		}
+
+		switch {
+		case set.Reps != nil && *set.Reps > 0:
+			prescription.TargetReps = strconv.Itoa(*set.Reps)
+		case set.DurationSeconds != nil && *set.DurationSeconds > 0:
+			prescription.TargetReps = fmt.Sprintf("%d sec", *set.DurationSeconds)
+		case set.DistanceMeters != nil && *set.DistanceMeters > 0:
+			prescription.TargetReps = fmt.Sprintf("%gm", roundTo(*set.DistanceMeters, 1))
+		}
+
+		if set.Weight != nil && *set.Weight > 0 {
+			weight := roundToIncrement(*set.Weight, weightStep)
+			prescription.Weight = &weight
+		}
+
+		if prescription.SetType == "" {
+			prescription.SetType = models.SetTypeWorking
+		}
+
+		if i+1 < len(exercise.sets) {
+			if rest, ok := exercise.restBefore[exercise.sets[i+1].ID]; ok {
+				rest = roundRest(rest, restStep)
+				prescription.RestSeconds = &rest
+			}
+		}
+
+		sets = append(sets, prescription)
+	}
+
+	return sets
+}
+
+// roundRest rounds a rest to the nearest step of seconds
+func roundRest(seconds, step int) int {
+	if step <= 0 {
+		return seconds
+	}
+	return int(math.Round(float64(seconds)/float64(step))) * step
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"onefit/backend/models"
 	"strings"
@@ -8,6 +9,13 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidTarget = errors.New("invalid target")
+	ErrInvalidGroup  = errors.New("invalid exercise group")
+
+	ErrTemplateNameTaken = errors.New("template with this name already exists")
+)
+
 type TemplateService struct {
 	db *gorm.DB
 }
@@ -17,10 +25,14 @@ func NewTemplateService(db *gorm.DB) *TemplateService {
 }
 
 // GetUserTemplates returns user's workout templates with optional filters
-func (ts *TemplateService) GetUserTemplates(userID uint, category string, includePublic bool) ([]models.WorkoutTemplate, error) {
+func (ts *TemplateService) GetUserTemplates(userID uint, category, tag string, includePublic bool) ([]models.WorkoutTemplate, error) {
 	var templates []models.WorkoutTemplate
 
-	query := ts.db.Model(&models.WorkoutTemplate{}).Preload("Exercises.Exercise")
+	query := ts.db.Model(&models.WorkoutTemplate{}).
+		Preload("Exercises.Exercise").
+		Preload("Exercises.Sets", orderBySetNumber).
+		Preload("Groups").
+		Preload("Tags", orderByName)
 
 	// Base condition: user's own templates
 	conditions := []string{"user_id = ?"}
@@ -38,6 +50,15 @@ func (ts *TemplateService) GetUserTemplates(userID uint, category string, includ
 		args = append(args, category)
 	}
 
+	// Filter by tag if provided
+	if tag != "" {
+		conditions = append(conditions, "id IN (?)")
+		args = append(args, ts.db.Table("workout_template_tags").
+			Select("workout_template_tags.workout_template_id").
+			Joins("JOIN tags ON workout_template_tags.tag_id = tags.id AND tags.deleted_at IS NULL").
+			Where("tags.name = ?", strings.ToLower(strings.TrimSpace(tag))))
+	}
+
 	// Build WHERE clause
 	whereClause := strings.Join(conditions, " AND ")
 
@@ -45,6 +66,10 @@ func (ts *TemplateService) GetUserTemplates(userID uint, category string, includ
 		Order("created_at DESC").
 		Find(&templates).Error
 
+	for i := range templates {
+		groupTemplateExercises(&templates[i])
+	}
+
 	return templates, err
 }
 
@@ -55,28 +80,45 @@ func (ts *TemplateService) GetTemplateWithExercises(userID, templateID uint) (*m
 	// User can access their own templates or public templates
 	err := ts.db.Where("id = ? AND (user_id = ? OR is_public = ?)", templateID, userID, true).
 		Preload("Exercises.Exercise").
+		Preload("Exercises.Sets", orderBySetNumber).
 		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
 			return db.Order("order_index ASC")
 		}).
+		Preload("Groups").
+		Preload("Tags", orderByName).
 		First(&template).Error
 
+	groupTemplateExercises(&template)
 	return &template, err
 }
 
-// CreateTemplate creates a new workout template
-func (ts *TemplateService) CreateTemplate(userID uint, name, description, category string, isPublic bool) (*models.WorkoutTemplate, error) {
-	// Validate required fields
-	if strings.TrimSpace(name) == "" {
-		return nil, fmt.Errorf("template name is required")
+// orderBySetNumber orders preloaded set prescriptions
+func orderBySetNumber(db *gorm.DB) *gorm.DB {
+	return db.Order("set_number ASC")
+}
+
+// groupTemplateExercises fills each group with its exercises from the template's list
+func groupTemplateExercises(template *models.WorkoutTemplate) {
+	for i := range template.Groups {
+		template.Groups[i].Exercises = []models.TemplateExercise{}
+		for _, templateExercise := range template.Exercises {
+			if templateExercise.GroupID != nil && *templateExercise.GroupID == template.Groups[i].ID {
+				template.Groups[i].Exercises = append(template.Groups[i].Exercises, templateExercise)
+			}
+		}
 	}
+}
 
-	// Check if template name already exists for this user
-	var existingTemplate models.WorkoutTemplate
-	err := ts.db.Where("name = ? AND user_id = ?", strings.TrimSpace(name), userID).First(&existingTemplate).Error
-	if err == nil {
-		return nil, fmt.Errorf("template with name '%s' already exists", strings.TrimSpace(name))
+// CreateTemplate creates a new workout template
+func (ts *TemplateService) CreateTemplate(userID uint, name, description, category string, isPublic bool, tagNames []string) (*models.WorkoutTemplate, error) {
+	// Validate the name and check it isn't already used by this user
+	err := ts.checkTemplateName(userID, strings.TrimSpace(name), 0)
+	if err != nil {
+		return nil, err
 	}
-	if err != gorm.ErrRecordNotFound {
+
+	tags, err := resolveTags(ts.db, userID, tagNames)
+	if err != nil {
 		return nil, err
 	}
 
@@ -87,6 +129,7 @@ func (ts *TemplateService) CreateTemplate(userID uint, name, description, catego
 		Description: strings.TrimSpace(description),
 		Category:    strings.TrimSpace(category),
 		IsPublic:    isPublic,
+		Tags:        tags,
 	}
 
 	err = ts.db.Create(&template).Error
@@ -98,23 +141,18 @@ func (ts *TemplateService) CreateTemplate(userID uint, name, description, catego
 }
 
 // UpdateTemplate updates an existing template
-func (ts *TemplateService) UpdateTemplate(userID, templateID uint, name, description, category *string, isPublic *bool) (*models.WorkoutTemplate, error) {
+func (ts *TemplateService) UpdateTemplate(userID, templateID uint, name, description, category *string, isPublic *bool, tagNames *[]string) (*models.WorkoutTemplate, error) {
 	// Find the template and verify ownership
 	var template models.WorkoutTemplate
-	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).Preload("Tags", orderByName).First(&template).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Update fields if provided
 	if name != nil && strings.TrimSpace(*name) != "" {
-		// Check if new name conflicts with existing templates for this user
-		var existingTemplate models.WorkoutTemplate
-		err := ts.db.Where("name = ? AND user_id = ? AND id != ?", strings.TrimSpace(*name), userID, templateID).First(&existingTemplate).Error
-		if err == nil {
-			return nil, fmt.Errorf("template with name '%s' already exists", strings.TrimSpace(*name))
-		}
-		if err != gorm.ErrRecordNotFound {
+		// Check if new name conflicts with the user's other templates
+		if err := ts.checkTemplateName(userID, strings.TrimSpace(*name), templateID); err != nil {
 			return nil, err
 		}
 		template.Name = strings.TrimSpace(*name)
@@ -133,7 +171,24 @@ func (ts *TemplateService) UpdateTemplate(userID, templateID uint, name, descrip
 	}
 
 	// Save the updates
-	err = ts.db.Save(&template).Error
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(&template).Error; err != nil {
+			return err
+		}
+		if tagNames == nil {
+			return nil
+		}
+
+		tags, err := resolveTags(tx, userID, *tagNames)
+		if err != nil {
+			return err
+		}
+		if err := replaceTags(tx, &template, tags); err != nil {
+			return err
+		}
+		template.Tags = tags
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -141,6 +196,29 @@ func (ts *TemplateService) UpdateTemplate(userID, templateID uint, name, descrip
 	return &template, nil
 }
 
+// checkTemplateName rejects a blank name or one the user already uses on a template
+// other than excludeID (0 when creating)
+func (ts *TemplateService) checkTemplateName(userID uint, name string, excludeID uint) error {
+	if name == "" {
+		return fmt.Errorf("template name is required")
+	}
+
+	query := ts.db.Where("name = ? AND user_id = ?", name, userID)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+
+	var existingTemplate models.WorkoutTemplate
+	err := query.First(&existingTemplate).Error
+	if err == nil {
+		return fmt.Errorf("%w: '%s'", ErrTemplateNameTaken, name)
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return nil
+}
+
 // DeleteTemplate deletes a template and its exercises
 func (ts *TemplateService) DeleteTemplate(userID, templateID uint) error {
 	// Check if template exists and is owned by user
@@ -161,15 +239,70 @@ func (ts *TemplateService) DeleteTemplate(userID, templateID uint) error {
 		return fmt.Errorf("cannot delete template: it is being used in workout sessions")
 	}
 
+	// Programs schedule templates on their days
+	var programDayCount int64
+	err = ts.db.Model(&models.ProgramDay{}).Where("template_id = ?", templateID).Count(&programDayCount).Error
+	if err != nil {
+		return err
+	}
+
+	if programDayCount > 0 {
+		return fmt.Errorf("cannot delete template: it is scheduled in a program")
+	}
+
+	// Rotations cycle through templates
+	var rotationCount int64
+	err = ts.db.Model(&models.RotationTemplate{}).Where("template_id = ?", templateID).Count(&rotationCount).Error
+	if err != nil {
+		return err
+	}
+
+	if rotationCount > 0 {
+		return fmt.Errorf("cannot delete template: it is part of a rotation")
+	}
+
+	// Calendar events plan workouts from templates
+	var eventCount int64
+	err = ts.db.Model(&models.ScheduledEvent{}).Where("template_id = ?", templateID).Count(&eventCount).Error
+	if err != nil {
+		return err
+	}
+
+	if eventCount > 0 {
+		return fmt.Errorf("cannot delete template: it is scheduled on the calendar")
+	}
+
+	// Ratings and saves go with the template
+	err = ts.db.Unscoped().Where("template_id = ?", templateID).Delete(&models.TemplateRating{}).Error
+	if err != nil {
+		return err
+	}
+	err = ts.db.Unscoped().Where("template_id = ?", templateID).Delete(&models.TemplateSave{}).Error
+	if err != nil {
+		return err
+	}
+
 	// Delete template (cascade will handle template_exercises)
 	return ts.db.Delete(&template).Error
 }
 
-// AddExerciseToTemplate adds an exercise to a template
-func (ts *TemplateService) AddExerciseToTemplate(userID, templateID, exerciseID uint, orderIndex, targetSets int, targetReps string, targetWeight *float64, restSeconds int) (*models.TemplateExercise, error) {
+// AddExerciseToTemplate adds an exercise to a template. When sets are given they
+// prescribe each set and target_sets follows their count.
+func (ts *TemplateService) AddExerciseToTemplate(userID, templateID, exerciseID uint, orderIndex, targetSets int, targetReps string, targetWeight *float64, restSeconds int, sets []models.TemplateSet) (*models.TemplateExercise, error) {
+	if err := validateTarget(targetReps); err != nil {
+		return nil, err
+	}
+	sets, err := prepareTemplateSets(sets)
+	if err != nil {
+		return nil, err
+	}
+	if len(sets) > 0 {
+		targetSets = len(sets)
+	}
+
 	// Verify template ownership
 	var template models.WorkoutTemplate
-	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	err = ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
 	if err != nil {
 		return nil, err
 	}
@@ -191,31 +324,39 @@ func (ts *TemplateService) AddExerciseToTemplate(userID, templateID, exerciseID
 		return nil, err
 	}
 
-	// If orderIndex is 0, set it to the next available position
-	if orderIndex == 0 {
-		var maxOrder int
-		ts.db.Model(&models.TemplateExercise{}).Where("template_id = ?", templateID).Select("COALESCE(MAX(order_index), 0)").Scan(&maxOrder)
-		orderIndex = maxOrder + 1
-	}
+	// Append the exercise, then move it into place if a position was given
+	var maxOrder int
+	ts.db.Model(&models.TemplateExercise{}).Where("template_id = ?", templateID).Select("COALESCE(MAX(order_index), 0)").Scan(&maxOrder)
 
 	// Create template exercise
 	templateExercise := models.TemplateExercise{
 		TemplateID:   templateID,
 		ExerciseID:   exerciseID,
-		OrderIndex:   orderIndex,
+		OrderIndex:   maxOrder + 1,
 		TargetSets:   targetSets,
 		TargetReps:   strings.TrimSpace(targetReps),
 		TargetWeight: targetWeight,
 		RestSeconds:  restSeconds,
+		Sets:         sets,
 	}
 
-	err = ts.db.Create(&templateExercise).Error
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&templateExercise).Error; err != nil {
+			return err
+		}
+		if orderIndex > 0 {
+			if err := moveTemplateExercise(tx, templateID, templateExercise.ID, orderIndex); err != nil {
+				return err
+			}
+		}
+		return refreshTemplateEstimate(tx, templateID)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Load the exercise data
-	err = ts.db.Preload("Exercise").First(&templateExercise, templateExercise.ID).Error
+	err = ts.db.Preload("Exercise").Preload("Sets", orderBySetNumber).First(&templateExercise, templateExercise.ID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -239,11 +380,43 @@ func (ts *TemplateService) RemoveExerciseFromTemplate(userID, templateID, exerci
 		return err
 	}
 
-	return ts.db.Delete(&templateExercise).Error
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&templateExercise).Error; err != nil {
+			return err
+		}
+
+		if err := compactTemplateExerciseOrder(tx, templateID); err != nil {
+			return err
+		}
+
+		if templateExercise.GroupID != nil {
+			if err := dissolveUndersizedTemplateGroup(tx, *templateExercise.GroupID); err != nil {
+				return err
+			}
+		}
+		return refreshTemplateEstimate(tx, templateID)
+	})
 }
 
-// UpdateTemplateExercise updates exercise details within a template
-func (ts *TemplateService) UpdateTemplateExercise(userID, templateID, exerciseID uint, orderIndex, targetSets *int, targetReps *string, targetWeight *float64, restSeconds *int) (*models.TemplateExercise, error) {
+// UpdateTemplateExercise updates exercise details within a template. Given sets
+// replace the existing prescriptions; an empty list removes them so the
+// single-target fields apply again.
+func (ts *TemplateService) UpdateTemplateExercise(userID, templateID, exerciseID uint, orderIndex, targetSets *int, targetReps *string, targetWeight *float64, restSeconds *int, sets *[]models.TemplateSet) (*models.TemplateExercise, error) {
+	if targetReps != nil {
+		if err := validateTarget(*targetReps); err != nil {
+			return nil, err
+		}
+	}
+
+	var prescriptions []models.TemplateSet
+	if sets != nil {
+		var err error
+		prescriptions, err = prepareTemplateSets(*sets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Verify template ownership
 	var template models.WorkoutTemplate
 	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
@@ -259,10 +432,6 @@ func (ts *TemplateService) UpdateTemplateExercise(userID, templateID, exerciseID
 	}
 
 	// Update fields if provided
-	if orderIndex != nil {
-		templateExercise.OrderIndex = *orderIndex
-	}
-
 	if targetSets != nil {
 		templateExercise.TargetSets = *targetSets
 	}
@@ -279,14 +448,46 @@ func (ts *TemplateService) UpdateTemplateExercise(userID, templateID, exerciseID
 		templateExercise.RestSeconds = *restSeconds
 	}
 
-	// Save the updates
-	err = ts.db.Save(&templateExercise).Error
+	if len(prescriptions) > 0 {
+		templateExercise.TargetSets = len(prescriptions)
+	}
+
+	// Save the updates, replacing the prescriptions when sets were given; a new
+	// position moves the exercise and renumbers the others
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&templateExercise).Error; err != nil {
+			return err
+		}
+
+		if orderIndex != nil {
+			if err := moveTemplateExercise(tx, templateID, templateExercise.ID, *orderIndex); err != nil {
+				return err
+			}
+		}
+
+		if sets != nil {
+			err := tx.Where("template_exercise_id = ?", templateExercise.ID).Delete(&models.TemplateSet{}).Error
+			if err != nil {
+				return err
+			}
+
+			for i := range prescriptions {
+				prescriptions[i].TemplateExerciseID = templateExercise.ID
+			}
+			if len(prescriptions) > 0 {
+				if err := tx.Create(&prescriptions).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return refreshTemplateEstimate(tx, templateID)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Load the exercise data
-	err = ts.db.Preload("Exercise").First(&templateExercise, templateExercise.ID).Error
+	err = ts.db.Preload("Exercise").Preload("Sets", orderBySetNumber).First(&templateExercise, templateExercise.ID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -294,6 +495,51 @@ func (ts *TemplateService) UpdateTemplateExercise(userID, templateID, exerciseID
 	return &templateExercise, nil
 }
 
+// ReorderTemplateExercises puts a template's exercises in the given order. Exercises
+// are identified by exercise ID, as in the other template exercise endpoints, and
+// the list must contain every exercise of the template exactly once.
+func (ts *TemplateService) ReorderTemplateExercises(userID, templateID uint, exerciseIDs []uint) (*models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		current, err := templateExerciseOrder(tx, templateID)
+		if err != nil {
+			return err
+		}
+
+		var templateExercises []models.TemplateExercise
+		err = tx.Select("id", "exercise_id").Where("id IN ?", current).Find(&templateExercises).Error
+		if err != nil {
+			return err
+		}
+
+		byExerciseID := make(map[uint]uint, len(templateExercises))
+		currentExerciseIDs := make([]uint, 0, len(templateExercises))
+		for _, templateExercise := range templateExercises {
+			byExerciseID[templateExercise.ExerciseID] = templateExercise.ID
+			currentExerciseIDs = append(currentExerciseIDs, templateExercise.ExerciseID)
+		}
+		if !isPermutation(currentExerciseIDs, exerciseIDs) {
+			return fmt.Errorf("%w: list every exercise of the template exactly once", ErrInvalidOrder)
+		}
+
+		ordered := make([]uint, 0, len(exerciseIDs))
+		for _, exerciseID := range exerciseIDs {
+			ordered = append(ordered, byExerciseID[exerciseID])
+		}
+		return writeTemplateExerciseOrder(tx, ordered)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.GetTemplateWithExercises(userID, templateID)
+}
+
 // GetTemplateCategories returns list of unique template categories
 func (ts *TemplateService) GetTemplateCategories(userID uint) ([]string, error) {
 	var categories []string
@@ -320,22 +566,26 @@ func (ts *TemplateService) DuplicateTemplate(userID, templateID uint, newName st
 	}
 
 	// Check if new name conflicts
-	var existingTemplate models.WorkoutTemplate
-	err = ts.db.Where("name = ? AND user_id = ?", strings.TrimSpace(newName), userID).First(&existingTemplate).Error
-	if err == nil {
-		return nil, fmt.Errorf("template with name '%s' already exists", strings.TrimSpace(newName))
+	err = ts.checkTemplateName(userID, strings.TrimSpace(newName), 0)
+	if err != nil {
+		return nil, err
 	}
-	if err != gorm.ErrRecordNotFound {
+
+	// Tags belong to the template's owner, so the copy gets the user's own tags
+	tags, err := resolveTags(ts.db, userID, tagNamesOf(originalTemplate.Tags))
+	if err != nil {
 		return nil, err
 	}
 
 	// Create new template
 	newTemplate := models.WorkoutTemplate{
-		UserID:      userID,
-		Name:        strings.TrimSpace(newName),
-		Description: originalTemplate.Description,
-		Category:    originalTemplate.Category,
-		IsPublic:    false, // Duplicated templates are private by default
+		UserID:       userID,
+		Name:         strings.TrimSpace(newName),
+		Description:  originalTemplate.Description,
+		Category:     originalTemplate.Category,
+		IsPublic:     false, // Duplicated templates are private by default
+		ForkedFromID: &originalTemplate.ID,
+		Tags:         tags,
 	}
 
 	err = ts.db.Create(&newTemplate).Error
@@ -343,16 +593,43 @@ func (ts *TemplateService) DuplicateTemplate(userID, templateID uint, newName st
 		return nil, err
 	}
 
+	// Copy groups, remembering the new ID of each
+	groupIDs := make(map[uint]uint, len(originalTemplate.Groups))
+	for _, group := range originalTemplate.Groups {
+		newGroup := models.TemplateExerciseGroup{
+			TemplateID:                  newTemplate.ID,
+			GroupType:                   group.GroupType,
+			Name:                        group.Name,
+			Rounds:                      group.Rounds,
+			RestBetweenExercisesSeconds: group.RestBetweenExercisesSeconds,
+			RestAfterRoundSeconds:       group.RestAfterRoundSeconds,
+		}
+
+		err = ts.db.Omit("Exercises").Create(&newGroup).Error
+		if err != nil {
+			return nil, err
+		}
+		groupIDs[group.ID] = newGroup.ID
+	}
+
 	// Copy all exercises
 	for _, templateExercise := range originalTemplate.Exercises {
+		var groupID *uint
+		if templateExercise.GroupID != nil {
+			newGroupID := groupIDs[*templateExercise.GroupID]
+			groupID = &newGroupID
+		}
+
 		newTemplateExercise := models.TemplateExercise{
 			TemplateID:   newTemplate.ID,
 			ExerciseID:   templateExercise.ExerciseID,
 			OrderIndex:   templateExercise.OrderIndex,
+			GroupID:      groupID,
 			TargetSets:   templateExercise.TargetSets,
 			TargetReps:   templateExercise.TargetReps,
 			TargetWeight: templateExercise.TargetWeight,
 			RestSeconds:  templateExercise.RestSeconds,
+			Sets:         copyTemplateSets(templateExercise.Sets),
 		}
 
 		err = ts.db.Create(&newTemplateExercise).Error
@@ -361,5 +638,319 @@ func (ts *TemplateService) DuplicateTemplate(userID, templateID uint, newName st
 		}
 	}
 
-	return &newTemplate, nil
+	err = refreshTemplateEstimate(ts.db, newTemplate.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.GetTemplateWithExercises(userID, newTemplate.ID)
+}
+
+// validateTarget rejects targets the parser can't understand so every stored
+// target has a structured form
+func validateTarget(targetReps string) error {
+	if _, err := models.ParseTarget(targetReps); err != nil {
+		return fmt.Errorf("%w '%s': %v", ErrInvalidTarget, strings.TrimSpace(targetReps), err)
+	}
+	return nil
+}
+
+// prepareTemplateSets validates set prescriptions and numbers them in the given
+// order. Each set needs a target and at most one load: an absolute weight, a
+// percentage of 1RM or an RPE, either as a field or after '@' in the target.
+func prepareTemplateSets(sets []models.TemplateSet) ([]models.TemplateSet, error) {
+	prepared := make([]models.TemplateSet, 0, len(sets))
+	for i, set := range sets {
+		setNumber := i + 1
+
+		set.TargetReps = strings.TrimSpace(set.TargetReps)
+		if set.TargetReps == "" {
+			return nil, fmt.Errorf("%w: set %d needs target_reps", ErrInvalidTarget, setNumber)
+		}
+		if err := validateTarget(set.TargetReps); err != nil {
+			return nil, fmt.Errorf("set %d: %w", setNumber, err)
+		}
+		target, _ := models.ParseTarget(set.TargetReps)
+
+		loads := 0
+		for _, load := range []*float64{set.Weight, set.PercentOneRepMax, set.RPE, target.PercentOneRepMax, target.RPE} {
+			if load != nil {
+				loads++
+			}
+		}
+		if loads > 1 {
+			return nil, fmt.Errorf("%w: set %d can have only one of weight, percent_one_rep_max or rpe", ErrInvalidTarget, setNumber)
+		}
+
+		if set.Weight != nil && *set.Weight < 0 {
+			return nil, fmt.Errorf("%w: set %d weight cannot be negative", ErrInvalidTarget, setNumber)
+		}
+		if set.PercentOneRepMax != nil && (*set.PercentOneRepMax <= 0 || *set.PercentOneRepMax > 150) {
+			return nil, fmt.Errorf("%w: set %d percentage of 1RM must be between 0 and 150", ErrInvalidTarget, setNumber)
+		}
+		if set.RPE != nil && (*set.RPE < 1 || *set.RPE > 10) {
+			return nil, fmt.Errorf("%w: set %d RPE must be between 1 and 10", ErrInvalidTarget, setNumber)
+		}
+		if set.RestSeconds != nil && *set.RestSeconds < 0 {
+			return nil, fmt.Errorf("%w: set %d rest cannot be negative", ErrInvalidTarget, setNumber)
+		}
+
+		// Loads written in the target are stored as fields so each has one place
+		if target.PercentOneRepMax != nil {
+			set.PercentOneRepMax = target.PercentOneRepMax
+		}
+		if target.RPE != nil {
+			set.RPE = target.RPE
+		}
+
+		if set.SetType == "" {
+			set.SetType = models.SetTypeWorking
+		}
+		if !models.IsValidSetType(set.SetType) {
+			return nil, fmt.Errorf("%w: set %d has unknown set type '%s'", ErrInvalidTarget, setNumber, set.SetType)
+		}
+
+		prepared = append(prepared, models.TemplateSet{
+			SetNumber:        setNumber,
+			TargetReps:       set.TargetReps,
+			Weight:           set.Weight,
+			PercentOneRepMax: set.PercentOneRepMax,
+			RPE:              set.RPE,
+			SetType:          set.SetType,
+			RestSeconds:      set.RestSeconds,
+		})
+	}
+
+	return prepared, nil
+}
+
+// copyTemplateSets copies set prescriptions for another template exercise
+func copyTemplateSets(sets []models.TemplateSet) []models.TemplateSet {
+	copies := make([]models.TemplateSet, 0, len(sets))
+	for _, set := range sets {
+		copies = append(copies, models.TemplateSet{
+			SetNumber:        set.SetNumber,
+			TargetReps:       set.TargetReps,
+			Weight:           set.Weight,
+			PercentOneRepMax: set.PercentOneRepMax,
+			RPE:              set.RPE,
+			SetType:          set.SetType,
+			RestSeconds:      set.RestSeconds,
+		})
+	}
+	return copies
+}
+
+// validateGroup checks a group's type, size, rounds and rest
+func validateGroup(groupType string, exerciseCount, rounds, restBetweenExercises, restAfterRound int) error {
+	minExercises := models.GroupMinExercises(groupType)
+	if minExercises == 0 {
+		return fmt.Errorf("%w: unknown group type '%s'", ErrInvalidGroup, groupType)
+	}
+	if exerciseCount < minExercises {
+		return fmt.Errorf("%w: a %s needs at least %d exercises", ErrInvalidGroup, groupType, minExercises)
+	}
+	if groupType == models.GroupSuperset && exerciseCount > 2 {
+		return fmt.Errorf("%w: a superset has exactly 2 exercises; use a giant_set for more", ErrInvalidGroup)
+	}
+	if rounds < 1 {
+		return fmt.Errorf("%w: rounds must be at least 1", ErrInvalidGroup)
+	}
+	if restBetweenExercises < 0 || restAfterRound < 0 {
+		return fmt.Errorf("%w: rest can't be negative", ErrInvalidGroup)
+	}
+	return nil
+}
+
+// dissolveUndersizedTemplateGroup removes a group once it has too few exercises left
+// for its type, leaving the remaining exercises ungrouped
+func dissolveUndersizedTemplateGroup(tx *gorm.DB, groupID uint) error {
+	var group models.TemplateExerciseGroup
+	err := tx.First(&group, groupID).Error
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	err = tx.Model(&models.TemplateExercise{}).Where("group_id = ?", groupID).Count(&count).Error
+	if err != nil || int(count) >= models.GroupMinExercises(group.GroupType) {
+		return err
+	}
+
+	err = tx.Model(&models.TemplateExercise{}).Where("group_id = ?", groupID).Update("group_id", nil).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(&group).Error
+}
+
+// CreateTemplateGroup groups exercises of a template (by exercise ID) into a superset,
+// giant set or circuit
+func (ts *TemplateService) CreateTemplateGroup(userID, templateID uint, groupType, name string, rounds, restBetweenExercises, restAfterRound int, exerciseIDs []uint) (*models.TemplateExerciseGroup, error) {
+	// Verify template ownership
+	var template models.WorkoutTemplate
+	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if rounds == 0 {
+		rounds = 1
+	}
+	exerciseIDs = uniqueIDs(exerciseIDs)
+	if err := validateGroup(groupType, len(exerciseIDs), rounds, restBetweenExercises, restAfterRound); err != nil {
+		return nil, err
+	}
+
+	var members []models.TemplateExercise
+	err = ts.db.Where("template_id = ? AND exercise_id IN ?", templateID, exerciseIDs).Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(members) != len(exerciseIDs) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	memberIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		if member.GroupID != nil {
+			return nil, fmt.Errorf("%w: exercise %d is already in a group", ErrInvalidGroup, member.ExerciseID)
+		}
+		memberIDs = append(memberIDs, member.ID)
+	}
+
+	group := models.TemplateExerciseGroup{
+		TemplateID:                  templateID,
+		GroupType:                   groupType,
+		Name:                        strings.TrimSpace(name),
+		Rounds:                      rounds,
+		RestBetweenExercisesSeconds: restBetweenExercises,
+		RestAfterRoundSeconds:       restAfterRound,
+	}
+
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Exercises").Create(&group).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.TemplateExercise{}).Where("id IN ?", memberIDs).Update("group_id", group.ID).Error
+		if err != nil {
+			return err
+		}
+		return refreshTemplateEstimate(tx, templateID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.getTemplateGroup(templateID, group.ID)
+}
+
+// UpdateTemplateGroup changes a group's type, name, rounds or rest
+func (ts *TemplateService) UpdateTemplateGroup(userID, templateID, groupID uint, groupType, name *string, rounds, restBetweenExercises, restAfterRound *int) (*models.TemplateExerciseGroup, error) {
+	// Verify template ownership
+	var template models.WorkoutTemplate
+	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := ts.getTemplateGroup(templateID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields if provided
+	if groupType != nil {
+		group.GroupType = *groupType
+	}
+
+	if name != nil {
+		group.Name = strings.TrimSpace(*name)
+	}
+
+	if rounds != nil {
+		group.Rounds = *rounds
+	}
+
+	if restBetweenExercises != nil {
+		group.RestBetweenExercisesSeconds = *restBetweenExercises
+	}
+
+	if restAfterRound != nil {
+		group.RestAfterRoundSeconds = *restAfterRound
+	}
+
+	err = validateGroup(group.GroupType, len(group.Exercises), group.Rounds, group.RestBetweenExercisesSeconds, group.RestAfterRoundSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Exercises").Save(group).Error; err != nil {
+			return err
+		}
+		return refreshTemplateEstimate(tx, templateID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// DeleteTemplateGroup removes a group; its exercises stay in the template ungrouped
+func (ts *TemplateService) DeleteTemplateGroup(userID, templateID, groupID uint) error {
+	// Verify template ownership
+	var template models.WorkoutTemplate
+	err := ts.db.Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error
+	if err != nil {
+		return err
+	}
+
+	var group models.TemplateExerciseGroup
+	err = ts.db.Where("id = ? AND template_id = ?", groupID, templateID).First(&group).Error
+	if err != nil {
+		return err
+	}
+
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.TemplateExercise{}).Where("group_id = ?", group.ID).Update("group_id", nil).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&group).Error; err != nil {
+			return err
+		}
+		return refreshTemplateEstimate(tx, templateID)
+	})
+}
+
+// getTemplateGroup loads a group of a template with its exercises
+func (ts *TemplateService) getTemplateGroup(templateID, groupID uint) (*models.TemplateExerciseGroup, error) {
+	var group models.TemplateExerciseGroup
+	err := ts.db.Where("id = ? AND template_id = ?", groupID, templateID).
+		Preload("Exercises.Exercise").
+		Preload("Exercises.Sets", orderBySetNumber).
+		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
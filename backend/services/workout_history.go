@@ -0,0 +1,183 @@
+package services
+
+import (
+	"onefit/backend/models"
+	"onefit/backend/queries"
+	"time"
+)
+
+// topMuscleGroupCount is how many muscle groups a history item lists
+const topMuscleGroupCount = 3
+
+// WorkoutHistoryItem is one row of the summary workout history, without exercises or sets
+type WorkoutHistoryItem struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	TemplateID      *uint      `json:"template_id"`
+	TemplateName    *string    `json:"template_name"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	ActiveSeconds   *int       `json:"active_seconds"` // nil while the workout is ongoing
+	AutoClosed      bool       `json:"auto_closed"`
+	ExerciseCount   int        `json:"exercise_count"`
+	TotalSets       int        `json:"total_sets"`   // warm-ups excluded
+	TotalVolume     float64    `json:"total_volume"` // weight × reps in kg, warm-ups excluded
+	TopMuscleGroups []string   `json:"top_muscle_groups" gorm:"-"`
+	Tags            []string   `json:"tags" gorm:"-"`
+	SummaryID       *uint      `json:"-"`
+}
+
+// exerciseTotals is a row of queries.GetWorkoutExerciseTotals
+type exerciseTotals struct {
+	SessionID         uint
+	SessionExerciseID uint
+	MuscleGroups      string
+	LoggedSets        int
+	WorkingSets       int
+	Volume            float64
+}
+
+// GetWorkoutHistory returns a page of the user's workouts with their totals only.
+// It runs a fixed number of queries however many workouts and sets there are:
+// one for the page, one each for the tags and the muscle groups of stored
+// summaries, and one aggregate for workouts that don't have a summary yet.
+func (ws *WorkoutService) GetWorkoutHistory(userID uint, limit, offset int, filter WorkoutFilter) ([]WorkoutHistoryItem, int64, error) {
+	matching, err := ws.filteredWorkouts(userID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := matching.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := []WorkoutHistoryItem{}
+	err = ws.db.Raw(queries.GetUserWorkoutHistory, userID, matching.Select("workout_sessions.id"), limit, offset).
+		Scan(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var workoutIDs, summaryIDs, unsummarizedIDs []uint
+	for _, item := range items {
+		workoutIDs = append(workoutIDs, item.ID)
+		if item.SummaryID != nil {
+			summaryIDs = append(summaryIDs, *item.SummaryID)
+		} else {
+			unsummarizedIDs = append(unsummarizedIDs, item.ID)
+		}
+	}
+
+	tags := make(map[uint][]string)
+	if len(workoutIDs) > 0 {
+		var workoutTags []struct {
+			WorkoutSessionID uint
+			Name             string
+		}
+		err := ws.taggedWorkouts().
+			Select("workout_session_tags.workout_session_id, tags.name").
+			Where("workout_session_tags.workout_session_id IN ?", workoutIDs).
+			Order("tags.name ASC").
+			Scan(&workoutTags).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, workoutTag := range workoutTags {
+			tags[workoutTag.WorkoutSessionID] = append(tags[workoutTag.WorkoutSessionID], workoutTag.Name)
+		}
+	}
+
+	topMuscleGroups := make(map[uint][]string)
+
+	// Stored summaries already rank their muscle groups
+	if len(summaryIDs) > 0 {
+		var muscleGroups []models.WorkoutSummaryMuscleGroup
+		err := orderByVolume(ws.db.Where("summary_id IN ?", summaryIDs)).Find(&muscleGroups).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, muscleGroup := range muscleGroups {
+			if len(topMuscleGroups[muscleGroup.SummaryID]) < topMuscleGroupCount {
+				topMuscleGroups[muscleGroup.SummaryID] = append(topMuscleGroups[muscleGroup.SummaryID], muscleGroup.MuscleGroup)
+			}
+		}
+	}
+
+	// Ongoing workouts and ones finished before summaries existed are totalled here
+	summarized := make(map[uint]WorkoutHistoryItem)
+	if len(unsummarizedIDs) > 0 {
+		var totals []exerciseTotals
+		err := ws.db.Raw(queries.GetWorkoutExerciseTotals, models.SetTypeWarmup, models.SetTypeWarmup, unsummarizedIDs).
+			Scan(&totals).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		summarized = totalWorkouts(totals)
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.SummaryID != nil {
+			item.TopMuscleGroups = topMuscleGroups[*item.SummaryID]
+		} else if totals, ok := summarized[item.ID]; ok {
+			item.ExerciseCount = totals.ExerciseCount
+			item.TotalSets = totals.TotalSets
+			item.TotalVolume = totals.TotalVolume
+			item.TopMuscleGroups = totals.TopMuscleGroups
+		}
+
+		if item.TopMuscleGroups == nil {
+			item.TopMuscleGroups = []string{}
+		}
+
+		item.Tags = tags[item.ID]
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+	}
+
+	return items, total, nil
+}
+
+// totalWorkouts adds up per-exercise totals by workout the same way summaries do
+func totalWorkouts(totals []exerciseTotals) map[uint]WorkoutHistoryItem {
+	items := make(map[uint]WorkoutHistoryItem)
+	muscleGroups := make(map[uint]map[string]*models.WorkoutSummaryMuscleGroup)
+
+	for _, exercise := range totals {
+		item := items[exercise.SessionID]
+		if exercise.LoggedSets > 0 {
+			item.ExerciseCount++
+		}
+		item.TotalSets += exercise.WorkingSets
+		item.TotalVolume += exercise.Volume
+		items[exercise.SessionID] = item
+
+		if muscleGroups[exercise.SessionID] == nil {
+			muscleGroups[exercise.SessionID] = make(map[string]*models.WorkoutSummaryMuscleGroup)
+		}
+		for _, name := range splitMuscleGroups(exercise.MuscleGroups) {
+			muscleGroup, ok := muscleGroups[exercise.SessionID][name]
+			if !ok {
+				muscleGroup = &models.WorkoutSummaryMuscleGroup{MuscleGroup: name}
+				muscleGroups[exercise.SessionID][name] = muscleGroup
+			}
+			muscleGroup.Sets += exercise.WorkingSets
+			muscleGroup.Volume += exercise.Volume
+		}
+	}
+
+	for sessionID, item := range items {
+		item.TotalVolume = roundTo(item.TotalVolume, 2)
+		for _, muscleGroup := range sortedMuscleGroups(muscleGroups[sessionID]) {
+			if muscleGroup.Sets == 0 || len(item.TopMuscleGroups) == topMuscleGroupCount {
+				break
+			}
+			item.TopMuscleGroups = append(item.TopMuscleGroups, muscleGroup.MuscleGroup)
+		}
+		items[sessionID] = item
+	}
+
+	return items
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"onefit/backend/models"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Size of the seeded history: every workout logs benchmarkSetsPerExercise sets of
+// each seeded exercise
+const (
+	benchmarkSessions        = 3000
+	benchmarkSetsPerExercise = 4
+)
+
+// benchmarkHistoryDB returns an in-memory database with a user whose history holds
+// benchmarkSessions finished workouts. Every other workout has a stored summary;
+// the rest look like workouts finished before summaries existed.
+func benchmarkHistoryDB(b *testing.B) (*gorm.DB, uint) {
+	b.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Every connection to :memory: opens a separate database
+	sqlDB.SetMaxOpenConns(1)
+	b.Cleanup(func() { sqlDB.Close() })
+
+	models.Migrate(db)
+	models.CreateIndexes(db)
+	if err := NewExerciseService(db).SeedDefaultExercises(); err != nil {
+		b.Fatal(err)
+	}
+
+	var exercises []models.Exercise
+	if err := db.Order("id ASC").Find(&exercises).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	user := models.User{FirebaseUID: "benchmark", Email: "benchmark@example.com", Weight: 80}
+	if err := db.Create(&user).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	start := time.Now().AddDate(0, 0, -benchmarkSessions)
+	for first := 0; first < benchmarkSessions; first += 100 {
+		var workouts []models.WorkoutSession
+		for day := first; day < min(first+100, benchmarkSessions); day++ {
+			startedAt := start.AddDate(0, 0, day)
+			endedAt := startedAt.Add(time.Hour)
+			activeSeconds := 3600
+
+			workout := models.WorkoutSession{
+				UserID:        user.ID,
+				Name:          fmt.Sprintf("Workout %d", day+1),
+				StartedAt:     startedAt,
+				EndedAt:       &endedAt,
+				ActiveSeconds: &activeSeconds,
+			}
+			for i, exercise := range exercises {
+				sessionExercise := models.SessionExercise{ExerciseID: exercise.ID, OrderIndex: i + 1}
+				for setNumber := 1; setNumber <= benchmarkSetsPerExercise; setNumber++ {
+					reps, weight := 8, float64(40+day%20)
+					sessionExercise.Sets = append(sessionExercise.Sets, models.ExerciseSet{
+						SetNumber:   setNumber,
+						SetType:     models.SetTypeWorking,
+						Reps:        &reps,
+						Weight:      &weight,
+						CompletedAt: startedAt.Add(time.Duration(i*benchmarkSetsPerExercise+setNumber) * 2 * time.Minute),
+					})
+				}
+				workout.Exercises = append(workout.Exercises, sessionExercise)
+			}
+			workouts = append(workouts, workout)
+		}
+		if err := db.Create(&workouts).Error; err != nil {
+			b.Fatal(err)
+		}
+
+		var summaries []models.WorkoutSummary
+		for i := 0; i < len(workouts); i += 2 {
+			workout := &workouts[i]
+			details := models.WorkoutSession{Base: workout.Base, Exercises: workout.Exercises, ActiveSeconds: workout.ActiveSeconds}
+			for j := range details.Exercises {
+				details.Exercises[j].Exercise = exercises[j]
+			}
+			summaries = append(summaries, summarizeWorkout(&details, user.Weight))
+		}
+		if err := db.Create(&summaries).Error; err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return db, user.ID
+}
+
+// BenchmarkGetUserWorkouts measures the full workout list, which preloads every
+// exercise and set of the page
+func BenchmarkGetUserWorkouts(b *testing.B) {
+	db, userID := benchmarkHistoryDB(b)
+	ws := NewWorkoutService(db)
+
+	for _, limit := range []int{20, 200} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				workouts, _, err := ws.GetUserWorkouts(userID, limit, 0, WorkoutFilter{})
+				if err != nil || len(workouts) != limit {
+					b.Fatalf("got %d workouts, %v", len(workouts), err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetWorkoutHistory measures the view=summary list over the same history
+func BenchmarkGetWorkoutHistory(b *testing.B) {
+	db, userID := benchmarkHistoryDB(b)
+	ws := NewWorkoutService(db)
+
+	for _, limit := range []int{20, 200} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				items, _, err := ws.GetWorkoutHistory(userID, limit, 0, WorkoutFilter{})
+				if err != nil || len(items) != limit {
+					b.Fatalf("got %d items, %v", len(items), err)
+				}
+			}
+		})
+	}
+}
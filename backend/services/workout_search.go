@@ -0,0 +1,65 @@
+package services
+
+import (
+	"onefit/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// WorkoutFilter narrows the workout history; empty fields don't filter
+type WorkoutFilter struct {
+	StartDate  string   // YYYY-MM-DD
+	EndDate    string   // YYYY-MM-DD
+	Search     string   // matched against the name, notes, tags, template name and exercise names
+	Tags       []string // workouts must carry every tag
+	ExerciseID *uint    // workouts that include the exercise
+}
+
+// filteredWorkouts returns a query for the user's workouts that match the filter
+func (ws *WorkoutService) filteredWorkouts(userID uint, filter WorkoutFilter) (*gorm.DB, error) {
+	query := ws.db.Model(&models.WorkoutSession{}).Where("workout_sessions.user_id = ?", userID)
+
+	if filter.StartDate != "" {
+		query = query.Where("workout_sessions.started_at >= ?", filter.StartDate+" 00:00:00")
+	}
+	if filter.EndDate != "" {
+		query = query.Where("workout_sessions.started_at <= ?", filter.EndDate+" 23:59:59")
+	}
+
+	if filter.ExerciseID != nil {
+		query = query.Where("workout_sessions.id IN (?)", ws.db.Model(&models.SessionExercise{}).
+			Select("session_id").
+			Where("exercise_id = ?", *filter.ExerciseID))
+	}
+
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		query = query.Where("workout_sessions.id IN (?)", ws.taggedWorkouts().Where("tags.name = ?", tag))
+	}
+
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		query = query.Where(ws.db.Where("workout_sessions.name LIKE ?", pattern).
+			Or("workout_sessions.notes LIKE ?", pattern).
+			Or("workout_sessions.id IN (?)", ws.taggedWorkouts().Where("tags.name LIKE ?", pattern)).
+			Or("workout_sessions.template_id IN (?)", ws.db.Model(&models.WorkoutTemplate{}).
+				Select("id").
+				Where("name LIKE ?", pattern)).
+			Or("workout_sessions.id IN (?)", ws.db.Model(&models.SessionExercise{}).
+				Select("session_exercises.session_id").
+				Joins("JOIN exercises ON session_exercises.exercise_id = exercises.id").
+				Where("exercises.name LIKE ?", pattern)))
+	}
+
+	return query, nil
+}
+
+// taggedWorkouts selects the IDs of workouts joined with their tags
+func (ws *WorkoutService) taggedWorkouts() *gorm.DB {
+	return ws.db.Table("workout_session_tags").
+		Select("workout_session_tags.workout_session_id").
+		Joins("JOIN tags ON workout_session_tags.tag_id = tags.id AND tags.deleted_at IS NULL")
+}
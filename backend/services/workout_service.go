@@ -1,13 +1,29 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"onefit/backend/models"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrPlannedSetUnavailable = errors.New("planned set not found or already completed")
+	ErrInvalidSetType        = errors.New("invalid set type")
+	ErrWorkoutEnded          = errors.New("workout has already ended")
+	ErrWorkoutNotEnded       = errors.New("workout has not ended yet")
+	ErrActiveWorkoutExists   = errors.New("an active workout is already in progress")
+	ErrWorkoutPaused         = errors.New("workout is already paused")
+	ErrWorkoutNotPaused      = errors.New("workout is not paused")
+	ErrInvalidTimestamp      = errors.New("invalid timestamp")
+	ErrMissingSetMetric      = errors.New("at least one set metric (reps, weight, duration, or distance) must be provided")
+	ErrWorkoutNameRequired   = errors.New("workout name is required")
+)
+
 type WorkoutService struct {
 	db *gorm.DB
 }
@@ -16,33 +32,39 @@ func NewWorkoutService(db *gorm.DB) *WorkoutService {
 	return &WorkoutService{db: db}
 }
 
-// GetUserWorkouts returns user's workout history with pagination and date filtering
-func (ws *WorkoutService) GetUserWorkouts(userID uint, limit, offset int, startDate, endDate string) ([]models.WorkoutSession, int64, error) {
+// GetUserWorkouts returns user's workout history with pagination, date filtering and search
+func (ws *WorkoutService) GetUserWorkouts(userID uint, limit, offset int, filter WorkoutFilter) ([]models.WorkoutSession, int64, error) {
 	var workouts []models.WorkoutSession
 	var total int64
 
-	query := ws.db.Model(&models.WorkoutSession{}).Where("user_id = ?", userID)
-
-	// Apply date filters if provided
-	if startDate != "" {
-		query = query.Where("started_at >= ?", startDate+" 00:00:00")
-	}
-	if endDate != "" {
-		query = query.Where("started_at <= ?", endDate+" 23:59:59")
+	query, err := ws.filteredWorkouts(userID, filter)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Get total count
 	query.Count(&total)
 
 	// Get paginated results with preloaded data
-	err := query.Preload("Template").
+	err = query.Preload("Template").
 		Preload("Exercises.Exercise").
 		Preload("Exercises.Sets").
+		Preload("Exercises.PlannedSets").
+		Preload("Groups").
+		Preload("Pauses").
+		Preload("Summary.MuscleGroups", orderByVolume).
+		Preload("Tags", orderByName).
 		Order("started_at DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&workouts).Error
 
+	now := time.Now()
+	for i := range workouts {
+		groupSessionExercises(&workouts[i])
+		applyWorkoutTiming(&workouts[i], now)
+	}
+
 	return workouts, total, err
 }
 
@@ -56,47 +78,134 @@ func (ws *WorkoutService) GetWorkoutWithDetails(userID, workoutID uint) (*models
 		Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB {
 			return db.Order("set_number ASC")
 		}).
+		Preload("Exercises.PlannedSets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_number ASC")
+		}).
 		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
 			return db.Order("order_index ASC")
 		}).
+		Preload("Groups").
+		Preload("Pauses", func(db *gorm.DB) *gorm.DB {
+			return db.Order("paused_at ASC")
+		}).
+		Preload("Summary.MuscleGroups", orderByVolume).
+		Preload("Tags", orderByName).
 		First(&workout).Error
 
+	groupSessionExercises(&workout)
+	applyWorkoutTiming(&workout, time.Now())
 	return &workout, err
 }
 
-// StartWorkout creates a new workout session
-func (ws *WorkoutService) StartWorkout(userID uint, name string, templateID *uint, notes string) (*models.WorkoutSession, error) {
-	// Validate name
-	if name == "" {
-		return nil, fmt.Errorf("workout name is required")
+// groupSessionExercises fills each group with its exercises from the workout's list
+func groupSessionExercises(workout *models.WorkoutSession) {
+	for i := range workout.Groups {
+		workout.Groups[i].Exercises = []models.SessionExercise{}
+		for _, sessionExercise := range workout.Exercises {
+			if sessionExercise.GroupID != nil && *sessionExercise.GroupID == workout.Groups[i].ID {
+				workout.Groups[i].Exercises = append(workout.Groups[i].Exercises, sessionExercise)
+			}
+		}
+	}
+}
+
+// StartWorkout creates a new workout session. Starting from a day of the user's
+// program uses the day's template and applies its week's progression; starting
+// from a rotation uses the template due next in it. The name then defaults to the
+// day's or template's name.
+func (ws *WorkoutService) StartWorkout(userID uint, name string, templateID *uint, notes string, tagNames []string, programDayID, rotationID *uint) (*models.WorkoutSession, error) {
+	if programDayID != nil && rotationID != nil {
+		return nil, fmt.Errorf("%w: start from a program day or a rotation, not both", ErrInvalidRotation)
+	}
+
+	if rotationID != nil {
+		nextTemplateID, err := rotationNextTemplateID(ws.db, userID, *rotationID)
+		if err != nil {
+			return nil, err
+		}
+		if templateID != nil && *templateID != nextTemplateID {
+			return nil, fmt.Errorf("%w: template_id isn't the rotation's next template", ErrInvalidRotation)
+		}
+		templateID = &nextTemplateID
+	}
+
+	var programDay *models.ProgramDay
+	var enrollmentID *uint
+	if programDayID != nil {
+		day, enrollment, err := enrolledProgramDay(ws.db, userID, *programDayID)
+		if err != nil {
+			return nil, err
+		}
+		if templateID != nil && *templateID != day.TemplateID {
+			return nil, fmt.Errorf("%w: template_id doesn't match the program day's template", ErrProgramDayUnavailable)
+		}
+		programDay = day
+		enrollmentID = &enrollment.ID
+		templateID = &day.TemplateID
 	}
 
 	// If starting from template, verify template exists and user has access
+	var template models.WorkoutTemplate
 	if templateID != nil {
-		var template models.WorkoutTemplate
 		err := ws.db.Where("id = ? AND (user_id = ? OR is_public = ?)", *templateID, userID, true).First(&template).Error
 		if err != nil {
 			return nil, fmt.Errorf("template not found or not accessible")
 		}
 	}
 
+	// Validate name
+	name = strings.TrimSpace(name)
+	if name == "" && programDay != nil {
+		name = programDay.Name
+	}
+	if name == "" && (programDay != nil || rotationID != nil) {
+		name = template.Name
+	}
+	if name == "" {
+		return nil, ErrWorkoutNameRequired
+	}
+
+	// Only one workout can be active at a time
+	activeID, err := ws.activeWorkoutID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if activeID != nil {
+		return nil, ErrActiveWorkoutExists
+	}
+
+	tags, err := resolveTags(ws.db, userID, tagNames)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create workout session
 	workout := models.WorkoutSession{
-		UserID:     userID,
-		TemplateID: templateID,
-		Name:       name,
-		StartedAt:  time.Now(),
-		Notes:      notes,
+		UserID:              userID,
+		TemplateID:          templateID,
+		Name:                name,
+		StartedAt:           time.Now(),
+		Notes:               notes,
+		Tags:                tags,
+		ProgramEnrollmentID: enrollmentID,
+		ProgramDayID:        programDayID,
 	}
 
-	err := ws.db.Create(&workout).Error
+	err = ws.db.Create(&workout).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another workout was started since the check above
+		return nil, ErrActiveWorkoutExists
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// If starting from template, copy template exercises to session
 	if templateID != nil {
-		err = ws.copyTemplateExercisesToSession(uint(workout.ID), *templateID)
+		err = ws.copyTemplateExercisesToSession(userID, uint(workout.ID), *templateID)
+		if err == nil && programDay != nil {
+			err = applyWeekProgression(ws.db, userID, workout.ID, programDay.Week)
+		}
 		if err != nil {
 			// Rollback workout creation if copying exercises fails
 			ws.db.Delete(&workout)
@@ -108,22 +217,424 @@ func (ws *WorkoutService) StartWorkout(userID uint, name string, templateID *uin
 	return ws.GetWorkoutWithDetails(userID, workout.ID)
 }
 
-// copyTemplateExercisesToSession copies exercises from template to workout session
-func (ws *WorkoutService) copyTemplateExercisesToSession(sessionID, templateID uint) error {
+// activeWorkoutID returns the ID of the user's ongoing workout, or nil if there is none
+func (ws *WorkoutService) activeWorkoutID(userID uint) (*uint, error) {
+	var workouts []models.WorkoutSession
+	err := ws.db.Where("user_id = ? AND ended_at IS NULL", userID).
+		Order("started_at DESC").
+		Limit(1).
+		Find(&workouts).Error
+	if err != nil || len(workouts) == 0 {
+		return nil, err
+	}
+	return &workouts[0].ID, nil
+}
+
+// RepeatWorkout starts a new workout cloned from a previous one: the same exercises
+// in the same order and groups, with the sets logged last time as planned sets.
+// It is refused while another workout is active.
+func (ws *WorkoutService) RepeatWorkout(userID, workoutID uint, name string) (*models.WorkoutSession, error) {
+	source, err := ws.GetWorkoutWithDetails(userID, workoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	activeID, err := ws.activeWorkoutID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if activeID != nil {
+		return nil, ErrActiveWorkoutExists
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = source.Name
+	}
+
+	workout := models.WorkoutSession{
+		UserID:     userID,
+		TemplateID: source.TemplateID,
+		Name:       strings.TrimSpace(name),
+		StartedAt:  time.Now(),
+		Tags:       source.Tags,
+	}
+
+	restBefore := make(map[uint]int)
+	for _, interval := range restIntervals(source) {
+		restBefore[interval.SetID] = interval.ActualSeconds
+	}
+
+	suggestion := fmt.Sprintf("Repeating your workout from %s", source.StartedAt.Format("Jan 2"))
+
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Exercises", "Groups", "Pauses").Create(&workout).Error; err != nil {
+			return err
+		}
+
+		groupIDs := make(map[uint]uint, len(source.Groups))
+		for _, group := range source.Groups {
+			newGroup := models.SessionExerciseGroup{
+				SessionID:                   workout.ID,
+				GroupType:                   group.GroupType,
+				Name:                        group.Name,
+				Rounds:                      group.Rounds,
+				RestBetweenExercisesSeconds: group.RestBetweenExercisesSeconds,
+				RestAfterRoundSeconds:       group.RestAfterRoundSeconds,
+			}
+			if err := tx.Omit("Exercises").Create(&newGroup).Error; err != nil {
+				return err
+			}
+			groupIDs[group.ID] = newGroup.ID
+		}
+
+		for i, sessionExercise := range source.Exercises {
+			var groupID *uint
+			if sessionExercise.GroupID != nil {
+				newGroupID := groupIDs[*sessionExercise.GroupID]
+				groupID = &newGroupID
+			}
+
+			newExercise := models.SessionExercise{
+				SessionID:   workout.ID,
+				ExerciseID:  sessionExercise.ExerciseID,
+				OrderIndex:  i + 1,
+				GroupID:     groupID,
+				Notes:       sessionExercise.Notes,
+				Suggestion:  suggestion,
+				PlannedSets: repeatPlannedSets(sessionExercise, restBefore),
+			}
+			if err := tx.Create(&newExercise).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another workout was started since the check above
+		return nil, ErrActiveWorkoutExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.GetWorkoutWithDetails(userID, workout.ID)
+}
+
+// repeatPlannedSets plans a repeated exercise from the sets logged last time. The
+// rest after each set is the one that was planned, or else the rest actually taken.
+// An exercise with no logged sets keeps its previous plan.
+func repeatPlannedSets(sessionExercise models.SessionExercise, restBefore map[uint]int) []models.PlannedSet {
+	if len(sessionExercise.Sets) == 0 {
+		plannedSets := make([]models.PlannedSet, 0, len(sessionExercise.PlannedSets))
+		for _, plannedSet := range sessionExercise.PlannedSets {
+			plannedSets = append(plannedSets, models.PlannedSet{
+				SetNumber:             plannedSet.SetNumber,
+				TargetReps:            plannedSet.TargetReps,
+				TargetWeight:          plannedSet.TargetWeight,
+				TargetDurationSeconds: plannedSet.TargetDurationSeconds,
+				TargetDistanceMeters:  plannedSet.TargetDistanceMeters,
+				TargetRPE:             plannedSet.TargetRPE,
+				RestSeconds:           plannedSet.RestSeconds,
+				SetType:               plannedSet.SetType,
+			})
+		}
+		return plannedSets
+	}
+
+	plannedSets := make([]models.PlannedSet, 0, len(sessionExercise.Sets))
+	for i, set := range sessionExercise.Sets {
+		plannedSet := models.PlannedSet{
+			SetNumber:             i + 1,
+			TargetReps:            set.Reps,
+			TargetWeight:          set.Weight,
+			TargetDurationSeconds: set.DurationSeconds,
+			TargetDistanceMeters:  set.DistanceMeters,
+			SetType:               set.SetType,
+		}
+		if set.RPE != nil {
+			rpe := float64(*set.RPE)
+			plannedSet.TargetRPE = &rpe
+		}
+		if plannedSet.SetType == "" {
+			plannedSet.SetType = models.SetTypeWorking
+		}
+
+		if rest := plannedRestAfter(set.ID, sessionExercise.PlannedSets); rest != nil {
+			plannedSet.RestSeconds = *rest
+		} else if i+1 < len(sessionExercise.Sets) {
+			plannedSet.RestSeconds = roundRest(restBefore[sessionExercise.Sets[i+1].ID], 15)
+		}
+
+		plannedSets = append(plannedSets, plannedSet)
+	}
+	return plannedSets
+}
+
+// PastExercise is an exercise of a workout entered after the fact
+type PastExercise struct {
+	ExerciseID uint
+	Notes      string
+	Sets       []PastSet
+}
+
+// PastSet is a set of a workout entered after the fact, with the time it was done
+type PastSet struct {
+	SetType         string
+	Reps            *int
+	Weight          *float64
+	DurationSeconds *int
+	DistanceMeters  *float64
+	RPE             *int
+	CompletedAt     time.Time
+}
+
+// maxClockSkew allows timestamps slightly ahead of the server's clock
+const maxClockSkew = time.Minute
+
+// LogPastWorkout creates a finished workout with its exercises and sets in one
+// transaction, for sessions logged after the fact. Every set must fall inside the
+// workout's start and end. A drop set continues from the previous set of its exercise.
+func (ws *WorkoutService) LogPastWorkout(userID uint, name, notes string, templateID *uint, startedAt, endedAt time.Time, exercises []PastExercise) (*models.WorkoutSession, error) {
+	if name == "" {
+		return nil, fmt.Errorf("workout name is required")
+	}
+	if !endedAt.After(startedAt) {
+		return nil, fmt.Errorf("%w: ended_at must be after started_at", ErrInvalidTimestamp)
+	}
+	if endedAt.After(time.Now().Add(maxClockSkew)) {
+		return nil, fmt.Errorf("%w: ended_at cannot be in the future", ErrInvalidTimestamp)
+	}
+	if endedAt.Sub(startedAt) > 24*time.Hour {
+		return nil, fmt.Errorf("%w: a workout cannot last more than 24 hours", ErrInvalidTimestamp)
+	}
+
+	for i, exercise := range exercises {
+		for j, set := range exercise.Sets {
+			if set.Reps == nil && set.Weight == nil && set.DurationSeconds == nil && set.DistanceMeters == nil {
+				return nil, fmt.Errorf("exercise %d, set %d: %w", i+1, j+1, ErrMissingSetMetric)
+			}
+			if set.CompletedAt.Before(startedAt) || set.CompletedAt.After(endedAt) {
+				return nil, fmt.Errorf("%w: exercise %d, set %d must be completed between started_at and ended_at", ErrInvalidTimestamp, i+1, j+1)
+			}
+			if set.SetType != "" && !models.IsValidSetType(set.SetType) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidSetType, set.SetType)
+			}
+		}
+
+		// Sets are numbered in the order they were done
+		sort.SliceStable(exercise.Sets, func(a, b int) bool {
+			return exercise.Sets[a].CompletedAt.Before(exercise.Sets[b].CompletedAt)
+		})
+		if len(exercise.Sets) > 0 && exercise.Sets[0].SetType == models.SetTypeDrop {
+			return nil, fmt.Errorf("%w: a drop set needs an earlier set of the same exercise as its parent", ErrInvalidSetType)
+		}
+	}
+
+	if templateID != nil {
+		var template models.WorkoutTemplate
+		err := ws.db.Where("id = ? AND (user_id = ? OR is_public = ?)", *templateID, userID, true).First(&template).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	workout := models.WorkoutSession{
+		UserID:     userID,
+		TemplateID: templateID,
+		Name:       name,
+		StartedAt:  startedAt,
+		EndedAt:    &endedAt,
+		Notes:      notes,
+	}
+	applyWorkoutTiming(&workout, endedAt)
+	durationMinutes := *workout.ActiveSeconds / 60
+	workout.DurationMinutes = &durationMinutes
+
+	err := ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workout).Error; err != nil {
+			return err
+		}
+
+		exerciseIDs := []uint{}
+		for i, exercise := range exercises {
+			var libraryExercise models.Exercise
+			if err := tx.First(&libraryExercise, exercise.ExerciseID).Error; err != nil {
+				return err
+			}
+
+			sessionExercise := models.SessionExercise{
+				SessionID:  workout.ID,
+				ExerciseID: exercise.ExerciseID,
+				OrderIndex: i + 1,
+				Notes:      exercise.Notes,
+			}
+			if len(exercise.Sets) > 0 {
+				sessionExercise.CompletedAt = &exercise.Sets[len(exercise.Sets)-1].CompletedAt
+			}
+			if err := tx.Create(&sessionExercise).Error; err != nil {
+				return err
+			}
+
+			var previousSetID *uint
+			for j, set := range exercise.Sets {
+				exerciseSet := models.ExerciseSet{
+					SessionExerciseID: sessionExercise.ID,
+					SetNumber:         j + 1,
+					Reps:              set.Reps,
+					Weight:            set.Weight,
+					DurationSeconds:   set.DurationSeconds,
+					DistanceMeters:    set.DistanceMeters,
+					RPE:               set.RPE,
+					SetType:           set.SetType,
+					CompletedAt:       set.CompletedAt,
+				}
+				if exerciseSet.SetType == "" {
+					exerciseSet.SetType = models.SetTypeWorking
+				}
+				if exerciseSet.SetType == models.SetTypeDrop {
+					exerciseSet.ParentSetID = previousSetID
+				}
+
+				if err := tx.Create(&exerciseSet).Error; err != nil {
+					return err
+				}
+				previousSetID = &exerciseSet.ID
+			}
+
+			exerciseIDs = append(exerciseIDs, exercise.ExerciseID)
+		}
+
+		// The past sets may set or beat records
+		recordService := NewPersonalRecordService(tx)
+		for _, exerciseID := range uniqueIDs(exerciseIDs) {
+			if err := recordService.RecalculateRecords(userID, exerciseID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := saveWorkoutSummary(tx, userID, workout.ID); err != nil {
+			return err
+		}
+		return refreshNextTemplateSummary(tx, userID, workout)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.GetWorkoutWithDetails(userID, workout.ID)
+}
+
+// validateSetTime checks that a set's completion time falls inside its workout:
+// after the start and before the end, or before now for an ongoing workout
+func validateSetTime(workout models.WorkoutSession, completedAt time.Time) error {
+	end := time.Now().Add(maxClockSkew)
+	if workout.EndedAt != nil {
+		end = *workout.EndedAt
+	}
+
+	if completedAt.Before(workout.StartedAt) || completedAt.After(end) {
+		return fmt.Errorf("%w: completed_at must fall between the workout's start and end", ErrInvalidTimestamp)
+	}
+	return nil
+}
+
+// copyTemplateExercisesToSession copies exercises from template to workout session,
+// planning each exercise's sets from the user's last performance
+func (ws *WorkoutService) copyTemplateExercisesToSession(userID, sessionID, templateID uint) error {
 	// Get template exercises
 	var templateExercises []models.TemplateExercise
-	err := ws.db.Where("template_id = ?", templateID).Order("order_index ASC").Find(&templateExercises).Error
+	err := ws.db.Where("template_id = ?", templateID).
+		Preload("Exercise").
+		Preload("Sets", orderBySetNumber).
+		Order("order_index ASC").
+		Find(&templateExercises).Error
 	if err != nil {
 		return err
 	}
 
+	progressionService := NewProgressionService(ws.db)
+	increments, err := progressionService.GetIncrements(userID)
+	if err != nil {
+		return err
+	}
+
+	// Copy supersets and circuits, remembering the session group for each template group
+	var templateGroups []models.TemplateExerciseGroup
+	err = ws.db.Where("template_id = ?", templateID).Find(&templateGroups).Error
+	if err != nil {
+		return err
+	}
+
+	groupIDs := make(map[uint]uint, len(templateGroups))
+	templateGroupsByID := make(map[uint]models.TemplateExerciseGroup, len(templateGroups))
+	for _, templateGroup := range templateGroups {
+		templateGroupsByID[templateGroup.ID] = templateGroup
+
+		sessionGroup := models.SessionExerciseGroup{
+			SessionID:                   sessionID,
+			GroupType:                   templateGroup.GroupType,
+			Name:                        templateGroup.Name,
+			Rounds:                      templateGroup.Rounds,
+			RestBetweenExercisesSeconds: templateGroup.RestBetweenExercisesSeconds,
+			RestAfterRoundSeconds:       templateGroup.RestAfterRoundSeconds,
+		}
+
+		err = ws.db.Omit("Exercises").Create(&sessionGroup).Error
+		if err != nil {
+			return err
+		}
+		groupIDs[templateGroup.ID] = sessionGroup.ID
+	}
+
+	// The last exercise of each group is followed by the rest between rounds
+	lastInGroup := make(map[uint]uint)
+	for _, templateExercise := range templateExercises {
+		if templateExercise.GroupID != nil {
+			lastInGroup[*templateExercise.GroupID] = templateExercise.ID
+		}
+	}
+
 	// Create session exercises from template exercises
 	for _, templateExercise := range templateExercises {
+		// Grouped exercises get one set per round and follow the group's rest rules
+		if templateExercise.GroupID != nil {
+			templateGroup := templateGroupsByID[*templateExercise.GroupID]
+			templateExercise.TargetSets = templateGroup.Rounds
+			templateExercise.RestSeconds = templateGroup.RestBetweenExercisesSeconds
+			if lastInGroup[templateGroup.ID] == templateExercise.ID {
+				templateExercise.RestSeconds = templateGroup.RestAfterRoundSeconds
+			}
+		}
+
+		plannedSets, suggestion, err := progressionService.SuggestSets(userID, templateExercise, increments)
+		if err != nil {
+			return err
+		}
+
+		var groupID *uint
+		if templateExercise.GroupID != nil {
+			sessionGroupID := groupIDs[*templateExercise.GroupID]
+			groupID = &sessionGroupID
+
+			// The group's rest also overrides per-set prescriptions
+			for i := range plannedSets {
+				plannedSets[i].RestSeconds = templateExercise.RestSeconds
+			}
+		}
+
+		notes := fmt.Sprintf("Target: %d sets of %s", templateExercise.TargetSets, templateExercise.TargetReps)
+		if len(templateExercise.Sets) > 0 {
+			notes = fmt.Sprintf("Target: %d prescribed sets", len(templateExercise.Sets))
+		}
+
 		sessionExercise := models.SessionExercise{
-			SessionID:  sessionID,
-			ExerciseID: templateExercise.ExerciseID,
-			OrderIndex: templateExercise.OrderIndex,
-			Notes:      fmt.Sprintf("Target: %d sets of %s", templateExercise.TargetSets, templateExercise.TargetReps),
+			SessionID:   sessionID,
+			ExerciseID:  templateExercise.ExerciseID,
+			OrderIndex:  templateExercise.OrderIndex,
+			GroupID:     groupID,
+			Notes:       notes,
+			Suggestion:  suggestion,
+			PlannedSets: plannedSets,
 		}
 
 		err = ws.db.Create(&sessionExercise).Error
@@ -132,14 +643,19 @@ func (ws *WorkoutService) copyTemplateExercisesToSession(sessionID, templateID u
 		}
 	}
 
-	return nil
+	// Templates edited before positions were kept contiguous may have gaps
+	return compactSessionExerciseOrder(ws.db, sessionID)
 }
 
-// UpdateWorkout updates workout details or finishes the workout
-func (ws *WorkoutService) UpdateWorkout(userID, workoutID uint, name, notes *string, endedAt *time.Time, isActive *bool) (*models.WorkoutSession, error) {
+// UpdateWorkout updates workout details or finishes the workout. Finishing ends any
+// ongoing pause and stores the elapsed and active time.
+func (ws *WorkoutService) UpdateWorkout(userID, workoutID uint, name, notes *string, endedAt *time.Time, isActive *bool, tagNames *[]string) (*models.WorkoutSession, error) {
 	// Find and verify ownership
 	var workout models.WorkoutSession
-	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).First(&workout).Error
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).
+		Preload("Pauses").
+		Preload("Tags", orderByName).
+		First(&workout).Error
 	if err != nil {
 		return nil, err
 	}
@@ -154,25 +670,135 @@ func (ws *WorkoutService) UpdateWorkout(userID, workoutID uint, name, notes *str
 	}
 
 	// Handle workout completion
-	if endedAt != nil {
-		workout.EndedAt = endedAt
-		// Calculate duration in minutes
-		duration := int(endedAt.Sub(workout.StartedAt).Minutes())
-		workout.DurationMinutes = &duration
-	} else if isActive != nil && !*isActive && workout.EndedAt == nil {
+	if endedAt == nil && isActive != nil && !*isActive && workout.EndedAt == nil {
 		// If marking as inactive and not already ended, set end time to now
 		now := time.Now()
-		workout.EndedAt = &now
-		duration := int(now.Sub(workout.StartedAt).Minutes())
-		workout.DurationMinutes = &duration
+		endedAt = &now
+	}
+
+	var openPause *models.WorkoutPause
+	if endedAt != nil {
+		openPause = finishWorkout(&workout, *endedAt)
+		workout.AutoClosed = false
 	}
 
 	// Save updates
-	err = ws.db.Save(&workout).Error
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Pauses", "Summary", "Tags").Save(&workout).Error; err != nil {
+			return err
+		}
+		if tagNames != nil {
+			tags, err := resolveTags(tx, userID, *tagNames)
+			if err != nil {
+				return err
+			}
+			if err := replaceTags(tx, &workout, tags); err != nil {
+				return err
+			}
+			workout.Tags = tags
+		}
+		if openPause != nil {
+			if err := tx.Save(openPause).Error; err != nil {
+				return err
+			}
+		}
+		if endedAt == nil {
+			return nil
+		}
+
+		workout.Summary, err = saveWorkoutSummary(tx, userID, workout.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	applyWorkoutTiming(&workout, time.Now())
+	return &workout, nil
+}
+
+// finishWorkout ends a workout at endedAt: it closes an ongoing pause, which is
+// returned for saving, and stores the elapsed and active time
+func finishWorkout(workout *models.WorkoutSession, endedAt time.Time) *models.WorkoutPause {
+	workout.EndedAt = &endedAt
+
+	var openPause *models.WorkoutPause
+	for i := range workout.Pauses {
+		if workout.Pauses[i].ResumedAt == nil {
+			openPause = &workout.Pauses[i]
+			resumedAt := endedAt
+			if resumedAt.Before(openPause.PausedAt) {
+				resumedAt = openPause.PausedAt
+			}
+			openPause.ResumedAt = &resumedAt
+		}
+	}
+
+	applyWorkoutTiming(workout, endedAt)
+	durationMinutes := *workout.ActiveSeconds / 60
+	workout.DurationMinutes = &durationMinutes
+
+	return openPause
+}
+
+// PauseWorkout starts a pause in an ongoing workout; paused time doesn't count as
+// active time or rest
+func (ws *WorkoutService) PauseWorkout(userID, workoutID uint) (*models.WorkoutSession, error) {
+	var workout models.WorkoutSession
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).Preload("Pauses").First(&workout).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if workout.EndedAt != nil {
+		return nil, ErrWorkoutEnded
+	}
+	applyWorkoutTiming(&workout, time.Now())
+	if workout.IsPaused {
+		return nil, ErrWorkoutPaused
+	}
+
+	pause := models.WorkoutPause{SessionID: workout.ID, PausedAt: time.Now()}
+	err = ws.db.Create(&pause).Error
+	if err != nil {
+		return nil, err
+	}
+
+	workout.Pauses = append(workout.Pauses, pause)
+	applyWorkoutTiming(&workout, time.Now())
+	return &workout, nil
+}
+
+// ResumeWorkout ends the ongoing pause of a workout
+func (ws *WorkoutService) ResumeWorkout(userID, workoutID uint) (*models.WorkoutSession, error) {
+	var workout models.WorkoutSession
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).Preload("Pauses").First(&workout).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if workout.EndedAt != nil {
+		return nil, ErrWorkoutEnded
+	}
+
+	var openPause *models.WorkoutPause
+	for i := range workout.Pauses {
+		if workout.Pauses[i].ResumedAt == nil {
+			openPause = &workout.Pauses[i]
+		}
+	}
+	if openPause == nil {
+		return nil, ErrWorkoutNotPaused
+	}
+
+	now := time.Now()
+	openPause.ResumedAt = &now
+	err = ws.db.Save(openPause).Error
 	if err != nil {
 		return nil, err
 	}
 
+	applyWorkoutTiming(&workout, now)
 	return &workout, nil
 }
 
@@ -185,8 +811,21 @@ func (ws *WorkoutService) DeleteWorkout(userID, workoutID uint) error {
 		return err
 	}
 
-	// Delete workout (cascade will handle exercises and sets)
-	return ws.db.Delete(&workout).Error
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		// Delete workout (cascade will handle exercises and sets)
+		if err := tx.Delete(&workout).Error; err != nil {
+			return err
+		}
+
+		// Records set during this workout no longer count
+		if err := NewPersonalRecordService(tx).recalculateSessionRecords(userID, workout.ID); err != nil {
+			return err
+		}
+		if workout.EndedAt == nil {
+			return nil
+		}
+		return refreshNextTemplateSummary(tx, userID, workout)
+	})
 }
 
 // AddExerciseToWorkout adds an exercise to a workout session
@@ -205,22 +844,27 @@ func (ws *WorkoutService) AddExerciseToWorkout(userID, workoutID, exerciseID uin
 		return nil, err
 	}
 
-	// If orderIndex is 0, set it to the next available position
-	if orderIndex == 0 {
-		var maxOrder int
-		ws.db.Model(&models.SessionExercise{}).Where("session_id = ?", workoutID).Select("COALESCE(MAX(order_index), 0)").Scan(&maxOrder)
-		orderIndex = maxOrder + 1
-	}
+	// Append the exercise, then move it into place if a position was given
+	var maxOrder int
+	ws.db.Model(&models.SessionExercise{}).Where("session_id = ?", workoutID).Select("COALESCE(MAX(order_index), 0)").Scan(&maxOrder)
 
 	// Create session exercise
 	sessionExercise := models.SessionExercise{
 		SessionID:  workoutID,
 		ExerciseID: exerciseID,
-		OrderIndex: orderIndex,
+		OrderIndex: maxOrder + 1,
 		Notes:      notes,
 	}
 
-	err = ws.db.Create(&sessionExercise).Error
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sessionExercise).Error; err != nil {
+			return err
+		}
+		if orderIndex <= 0 {
+			return nil
+		}
+		return moveSessionExercise(tx, workoutID, sessionExercise.ID, orderIndex)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -246,10 +890,6 @@ func (ws *WorkoutService) UpdateSessionExercise(userID, workoutID, sessionExerci
 	}
 
 	// Update fields if provided
-	if orderIndex != nil {
-		sessionExercise.OrderIndex = *orderIndex
-	}
-
 	if notes != nil {
 		sessionExercise.Notes = *notes
 	}
@@ -258,8 +898,16 @@ func (ws *WorkoutService) UpdateSessionExercise(userID, workoutID, sessionExerci
 		sessionExercise.CompletedAt = completedAt
 	}
 
-	// Save updates
-	err = ws.db.Save(&sessionExercise).Error
+	// Save updates; a new position moves the exercise and renumbers the others
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&sessionExercise).Error; err != nil {
+			return err
+		}
+		if orderIndex == nil {
+			return nil
+		}
+		return moveSessionExercise(tx, workoutID, sessionExercise.ID, *orderIndex)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -273,49 +921,196 @@ func (ws *WorkoutService) UpdateSessionExercise(userID, workoutID, sessionExerci
 	return &sessionExercise, nil
 }
 
-// LogSet adds a set to an exercise in the workout
-func (ws *WorkoutService) LogSet(userID, workoutID, sessionExerciseID uint, reps *int, weight *float64, durationSeconds *int, distanceMeters *float64, rpe *int) (*models.ExerciseSet, error) {
+// LogSet adds a set to an exercise in the workout. If the exercise has planned sets,
+// the set fills the given planned slot or the next open one of the requested type;
+// drop sets fill a slot only when given one. Metrics and set type left out are
+// taken from the slot.
+func (ws *WorkoutService) LogSet(userID, workoutID, sessionExerciseID uint, plannedSetID *uint, setType string, parentSetID *uint, reps *int, weight *float64, durationSeconds *int, distanceMeters *float64, rpe *int, completedAt *time.Time) (*models.ExerciseSet, error) {
 	// Verify session exercise ownership
 	var sessionExercise models.SessionExercise
 	err := ws.db.Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id").
 		Where("session_exercises.id = ? AND session_exercises.session_id = ? AND workout_sessions.user_id = ?", sessionExerciseID, workoutID, userID).
+		Preload("Session").
 		First(&sessionExercise).Error
 	if err != nil {
 		return nil, err
 	}
 
-	// Validate that at least one metric is provided
-	if reps == nil && weight == nil && durationSeconds == nil && distanceMeters == nil {
-		return nil, fmt.Errorf("at least one set metric (reps, weight, duration, or distance) must be provided")
+	// Sets are stamped now unless an earlier time inside the workout is given
+	setTime := time.Now()
+	if completedAt != nil {
+		if err := validateSetTime(sessionExercise.Session, *completedAt); err != nil {
+			return nil, err
+		}
+		setTime = *completedAt
 	}
 
-	// Get next set number
-	var maxSetNumber int
-	ws.db.Model(&models.ExerciseSet{}).Where("session_exercise_id = ?", sessionExerciseID).Select("COALESCE(MAX(set_number), 0)").Scan(&maxSetNumber)
-	setNumber := maxSetNumber + 1
+	var exerciseSet models.ExerciseSet
+	var plannedSet *models.PlannedSet
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		// Find the planned slot this set fills. A drop set follows the set before
+		// it, so it only fills a slot it is given.
+		var openSlots []models.PlannedSet
+		if plannedSetID != nil || setType != models.SetTypeDrop {
+			query := tx.Where("session_exercise_id = ? AND completed = ?", sessionExerciseID, false)
+			if plannedSetID != nil {
+				query = query.Where("id = ?", *plannedSetID)
+			} else if setType != "" {
+				query = query.Where("set_type = ?", setType)
+			}
+			if err := query.Order("set_number ASC").Limit(1).Find(&openSlots).Error; err != nil {
+				return err
+			}
+		}
+		if plannedSetID != nil && len(openSlots) == 0 {
+			return ErrPlannedSetUnavailable
+		}
 
-	// Create exercise set
-	exerciseSet := models.ExerciseSet{
-		SessionExerciseID: sessionExerciseID,
-		SetNumber:         setNumber,
-		Reps:              reps,
-		Weight:            weight,
-		DurationSeconds:   durationSeconds,
-		DistanceMeters:    distanceMeters,
-		RPE:               rpe,
-		CompletedAt:       time.Now(),
+		if len(openSlots) > 0 {
+			plannedSet = &openSlots[0]
+
+			// Completing a planned set as prescribed needs no metrics
+			if reps == nil && weight == nil && durationSeconds == nil && distanceMeters == nil {
+				reps = plannedSet.TargetReps
+				weight = plannedSet.TargetWeight
+				durationSeconds = plannedSet.TargetDurationSeconds
+				distanceMeters = plannedSet.TargetDistanceMeters
+			}
+
+			if setType == "" {
+				setType = plannedSet.SetType
+			}
+		}
+
+		if setType == "" {
+			setType = models.SetTypeWorking
+		}
+
+		// Validate that at least one metric is provided
+		if reps == nil && weight == nil && durationSeconds == nil && distanceMeters == nil {
+			return ErrMissingSetMetric
+		}
+
+		// Planned sets keep their position; anything beyond the plan is appended
+		// after both the logged and the planned sets
+		var setNumber int
+		if plannedSet != nil {
+			setNumber = plannedSet.SetNumber
+		} else {
+			var maxSetNumber, maxPlannedNumber int
+			tx.Model(&models.ExerciseSet{}).Where("session_exercise_id = ?", sessionExerciseID).Select("COALESCE(MAX(set_number), 0)").Scan(&maxSetNumber)
+			tx.Model(&models.PlannedSet{}).Where("session_exercise_id = ?", sessionExerciseID).Select("COALESCE(MAX(set_number), 0)").Scan(&maxPlannedNumber)
+			setNumber = max(maxSetNumber, maxPlannedNumber) + 1
+		}
+
+		// Create exercise set
+		exerciseSet = models.ExerciseSet{
+			SessionExerciseID: sessionExerciseID,
+			SetNumber:         setNumber,
+			Reps:              reps,
+			Weight:            weight,
+			DurationSeconds:   durationSeconds,
+			DistanceMeters:    distanceMeters,
+			RPE:               rpe,
+			CompletedAt:       setTime,
+		}
+
+		if err := NewWorkoutService(tx).applySetType(&exerciseSet, setType, parentSetID); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&exerciseSet).Error; err != nil {
+			return err
+		}
+
+		// Claim the slot only if no other set filled it in the meantime
+		if plannedSet != nil {
+			result := tx.Model(&models.PlannedSet{}).
+				Where("id = ? AND exercise_set_id IS NULL", plannedSet.ID).
+				Updates(map[string]interface{}{"completed": true, "exercise_set_id": exerciseSet.ID})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrPlannedSetUnavailable
+			}
+			plannedSet.Completed = true
+			plannedSet.ExerciseSetID = &exerciseSet.ID
+		}
+
+		// Check the new set against the records that currently stand
+		recordService := NewPersonalRecordService(tx)
+		if err := recordService.RecordSet(userID, sessionExercise.ExerciseID, workoutID, exerciseSet); err != nil {
+			return err
+		}
+		if err := recordService.AttachSetRecords(&exerciseSet); err != nil {
+			return err
+		}
+		return refreshWorkoutSummary(tx, userID, workoutID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err = ws.db.Create(&exerciseSet).Error
+	exerciseSet.PlannedSet = plannedSet
+
+	// Start the rest timer from the server's clock so every device counts down
+	// together; sets logged after the fact don't start one
+	if completedAt != nil {
+		return &exerciseSet, nil
+	}
+
+	var plannedSets []models.PlannedSet
+	err = ws.db.Where("session_exercise_id = ?", sessionExerciseID).Find(&plannedSets).Error
 	if err != nil {
 		return nil, err
 	}
+	exerciseSet.RestTimerEndsAt = restTimerEnd(exerciseSet.CompletedAt, plannedRestAfter(exerciseSet.ID, plannedSets))
 
 	return &exerciseSet, nil
 }
 
+// applySetType validates the set type and links a drop set to its parent: the given
+// set, the current parent, or else the set of the same exercise logged just before it
+func (ws *WorkoutService) applySetType(exerciseSet *models.ExerciseSet, setType string, parentSetID *uint) error {
+	if !models.IsValidSetType(setType) {
+		return fmt.Errorf("%w: %s", ErrInvalidSetType, setType)
+	}
+	exerciseSet.SetType = setType
+
+	if setType != models.SetTypeDrop {
+		exerciseSet.ParentSetID = nil
+		return nil
+	}
+
+	query := ws.db.Where("session_exercise_id = ?", exerciseSet.SessionExerciseID)
+	if exerciseSet.ID != 0 {
+		query = query.Where("id <> ?", exerciseSet.ID)
+	}
+
+	if parentSetID != nil {
+		query = query.Where("id = ?", *parentSetID)
+	} else if exerciseSet.ParentSetID != nil {
+		query = query.Where("id = ?", *exerciseSet.ParentSetID)
+	} else {
+		query = query.Where("completed_at <= ?", exerciseSet.CompletedAt).Order("completed_at DESC, id DESC")
+	}
+
+	var parent models.ExerciseSet
+	err := query.First(&parent).Error
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("%w: a drop set needs an earlier set of the same exercise as its parent", ErrInvalidSetType)
+	}
+	if err != nil {
+		return err
+	}
+
+	exerciseSet.ParentSetID = &parent.ID
+	return nil
+}
+
 // UpdateSet updates a logged set
-func (ws *WorkoutService) UpdateSet(userID, workoutID, setID uint, reps *int, weight *float64, durationSeconds *int, distanceMeters *float64, rpe *int) (*models.ExerciseSet, error) {
+func (ws *WorkoutService) UpdateSet(userID, workoutID, setID uint, setType *string, parentSetID *uint, reps *int, weight *float64, durationSeconds *int, distanceMeters *float64, rpe *int, completedAt *time.Time) (*models.ExerciseSet, error) {
 	// Verify set ownership through workout session
 	var exerciseSet models.ExerciseSet
 	err := ws.db.Joins("JOIN session_exercises ON exercise_sets.session_exercise_id = session_exercises.id").
@@ -347,8 +1142,50 @@ func (ws *WorkoutService) UpdateSet(userID, workoutID, setID uint, reps *int, we
 		exerciseSet.RPE = rpe
 	}
 
-	// Save updates
-	err = ws.db.Save(&exerciseSet).Error
+	if completedAt != nil {
+		var workout models.WorkoutSession
+		err = ws.db.First(&workout, workoutID).Error
+		if err != nil {
+			return nil, err
+		}
+		if err := validateSetTime(workout, *completedAt); err != nil {
+			return nil, err
+		}
+		exerciseSet.CompletedAt = *completedAt
+	}
+
+	if setType != nil || parentSetID != nil {
+		newSetType := exerciseSet.SetType
+		if setType != nil {
+			newSetType = *setType
+		}
+		if err := ws.applySetType(&exerciseSet, newSetType, parentSetID); err != nil {
+			return nil, err
+		}
+	}
+
+	var sessionExercise models.SessionExercise
+	err = ws.db.First(&sessionExercise, exerciseSet.SessionExerciseID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		// Save updates
+		if err := tx.Save(&exerciseSet).Error; err != nil {
+			return err
+		}
+
+		// The edit may create, move or remove records
+		recordService := NewPersonalRecordService(tx)
+		if err := recordService.RecalculateRecords(userID, sessionExercise.ExerciseID); err != nil {
+			return err
+		}
+		if err := recordService.AttachSetRecords(&exerciseSet); err != nil {
+			return err
+		}
+		return refreshWorkoutSummary(tx, userID, workoutID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -368,8 +1205,73 @@ func (ws *WorkoutService) DeleteSet(userID, workoutID, setID uint) error {
 		return err
 	}
 
-	// Delete the set
-	return ws.db.Delete(&exerciseSet).Error
+	var sessionExercise models.SessionExercise
+	err = ws.db.First(&sessionExercise, exerciseSet.SessionExerciseID).Error
+	if err != nil {
+		return err
+	}
+
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		// Delete the set
+		if err := tx.Delete(&exerciseSet).Error; err != nil {
+			return err
+		}
+
+		// Reopen the planned slot the set filled
+		err := tx.Model(&models.PlannedSet{}).
+			Where("exercise_set_id = ?", exerciseSet.ID).
+			Updates(map[string]interface{}{"completed": false, "exercise_set_id": nil}).Error
+		if err != nil {
+			return err
+		}
+
+		// Drop sets that continued from this set lose their parent
+		err = tx.Model(&models.ExerciseSet{}).Where("parent_set_id = ?", exerciseSet.ID).Update("parent_set_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		// A deleted set can no longer hold a record
+		if err := NewPersonalRecordService(tx).RecalculateRecords(userID, sessionExercise.ExerciseID); err != nil {
+			return err
+		}
+		return refreshWorkoutSummary(tx, userID, workoutID)
+	})
+}
+
+// GetWorkoutRest measures the rest taken before each set of a workout and compares
+// it with the prescribed rest, for the whole session and per exercise
+func (ws *WorkoutService) GetWorkoutRest(userID, workoutID uint) (*WorkoutRestStats, error) {
+	workout, err := ws.GetWorkoutWithDetails(userID, workoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := restIntervals(workout)
+	stats := &WorkoutRestStats{
+		WorkoutID: workout.ID,
+		Summary:   summarizeRest(intervals),
+		Exercises: []ExerciseRestStats{},
+		Sets:      intervals,
+	}
+
+	for _, sessionExercise := range workout.Exercises {
+		var exerciseIntervals []SetRest
+		for _, interval := range intervals {
+			if interval.SessionExerciseID == sessionExercise.ID {
+				exerciseIntervals = append(exerciseIntervals, interval)
+			}
+		}
+
+		stats.Exercises = append(stats.Exercises, ExerciseRestStats{
+			SessionExerciseID: sessionExercise.ID,
+			ExerciseID:        sessionExercise.ExerciseID,
+			ExerciseName:      sessionExercise.Exercise.Name,
+			RestSummary:       summarizeRest(exerciseIntervals),
+		})
+	}
+
+	return stats, nil
 }
 
 // GetActiveWorkout returns user's currently active workout (if any)
@@ -377,25 +1279,37 @@ func (ws *WorkoutService) GetActiveWorkout(userID uint) (*models.WorkoutSession,
 	var workout models.WorkoutSession
 
 	err := ws.db.Where("user_id = ? AND ended_at IS NULL", userID).
+		Order("started_at DESC").
 		Preload("Template").
 		Preload("Exercises.Exercise").
 		Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB {
 			return db.Order("set_number ASC")
 		}).
+		Preload("Exercises.PlannedSets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_number ASC")
+		}).
 		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
 			return db.Order("order_index ASC")
 		}).
+		Preload("Groups").
+		Preload("Pauses", func(db *gorm.DB) *gorm.DB {
+			return db.Order("paused_at ASC")
+		}).
+		Preload("Tags", orderByName).
 		First(&workout).Error
 
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil // No active workout is not an error
 	}
 
+	groupSessionExercises(&workout)
+	applyWorkoutTiming(&workout, time.Now())
 	return &workout, err
 }
 
-// GetWorkoutStats returns workout statistics for a user
-func (ws *WorkoutService) GetWorkoutStats(userID uint, days int) (map[string]interface{}, error) {
+// GetWorkoutStats returns workout statistics for a user. Warm-up sets are left out
+// of set counts and volume unless includeWarmups is set.
+func (ws *WorkoutService) GetWorkoutStats(userID uint, days int, includeWarmups bool) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
 	// Calculate date range
@@ -411,39 +1325,96 @@ func (ws *WorkoutService) GetWorkoutStats(userID uint, days int) (map[string]int
 		return nil, err
 	}
 
-	// Total workout time in minutes
-	var totalMinutes int
+	// Total active workout time; workouts finished before pauses were tracked only have minutes
+	var totalMinutes, totalActiveSeconds int
 	ws.db.Model(&models.WorkoutSession{}).
 		Where("user_id = ? AND started_at >= ? AND ended_at IS NOT NULL", userID, startDate).
 		Select("COALESCE(SUM(duration_minutes), 0)").
 		Scan(&totalMinutes)
+	ws.db.Model(&models.WorkoutSession{}).
+		Where("user_id = ? AND started_at >= ? AND ended_at IS NOT NULL", userID, startDate).
+		Select("COALESCE(SUM(COALESCE(active_seconds, duration_minutes * 60)), 0)").
+		Scan(&totalActiveSeconds)
+
+	// Sets logged in the period
+	setsInPeriod := func() *gorm.DB {
+		return ws.db.Model(&models.ExerciseSet{}).
+			Joins("JOIN session_exercises ON exercise_sets.session_exercise_id = session_exercises.id").
+			Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id").
+			Where("workout_sessions.user_id = ? AND workout_sessions.started_at >= ?", userID, startDate)
+	}
+
+	var warmupSets int64
+	err = setsInPeriod().Where("exercise_sets.set_type = ?", models.SetTypeWarmup).Count(&warmupSets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	countedSets := func() *gorm.DB {
+		query := setsInPeriod()
+		if !includeWarmups {
+			query = query.Where("exercise_sets.set_type IS NULL OR exercise_sets.set_type <> ?", models.SetTypeWarmup)
+		}
+		return query
+	}
 
 	// Total sets logged
 	var totalSets int64
-	err = ws.db.Model(&models.ExerciseSet{}).
-		Joins("JOIN session_exercises ON exercise_sets.session_exercise_id = session_exercises.id").
-		Joins("JOIN workout_sessions ON session_exercises.session_id = workout_sessions.id").
-		Where("workout_sessions.user_id = ? AND workout_sessions.started_at >= ?", userID, startDate).
-		Count(&totalSets).Error
+	err = countedSets().Count(&totalSets).Error
 	if err != nil {
 		return nil, err
 	}
 
+	// Total volume (weight × reps) in kg
+	var totalVolume float64
+	countedSets().Select("COALESCE(SUM(exercise_sets.weight * exercise_sets.reps), 0)").Scan(&totalVolume)
+
 	// Average workout duration
-	var avgDuration float64
+	var avgDuration, avgActiveSeconds float64
 	if totalWorkouts > 0 {
 		avgDuration = float64(totalMinutes) / float64(totalWorkouts)
+		avgActiveSeconds = roundTo(float64(totalActiveSeconds)/float64(totalWorkouts), 1)
 	}
 
 	stats["total_workouts"] = totalWorkouts
 	stats["total_minutes"] = totalMinutes
 	stats["total_sets"] = totalSets
+	stats["warmup_sets"] = warmupSets
+	stats["total_volume"] = roundTo(totalVolume, 2)
 	stats["average_duration_minutes"] = avgDuration
+	stats["total_active_seconds"] = totalActiveSeconds
+	stats["average_active_seconds"] = avgActiveSeconds
 	stats["period_days"] = days
 
 	return stats, nil
 }
 
+// ReorderWorkoutExercises puts a workout's exercises in the given order. The list
+// must contain every session exercise of the workout exactly once.
+func (ws *WorkoutService) ReorderWorkoutExercises(userID, workoutID uint, sessionExerciseIDs []uint) (*models.WorkoutSession, error) {
+	var workout models.WorkoutSession
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).First(&workout).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		current, err := sessionExerciseOrder(tx, workoutID)
+		if err != nil {
+			return err
+		}
+		if !isPermutation(current, sessionExerciseIDs) {
+			return fmt.Errorf("%w: list every exercise of the workout exactly once", ErrInvalidOrder)
+		}
+		return writeSessionExerciseOrder(tx, sessionExerciseIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.GetWorkoutWithDetails(userID, workoutID)
+}
+
 func (ws *WorkoutService) RemoveExerciseFromWorkout(userID, workoutID, sessionExerciseID uint) error {
 	// Verify session exercise ownership
 	var sessionExercise models.SessionExercise
@@ -454,6 +1425,135 @@ func (ws *WorkoutService) RemoveExerciseFromWorkout(userID, workoutID, sessionEx
 		return err
 	}
 
-	// Delete session exercise (cascade will handle sets)
-	return ws.db.Delete(&sessionExercise).Error
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		// Delete session exercise (cascade will handle sets)
+		if err := tx.Delete(&sessionExercise).Error; err != nil {
+			return err
+		}
+
+		if err := compactSessionExerciseOrder(tx, sessionExercise.SessionID); err != nil {
+			return err
+		}
+
+		if sessionExercise.GroupID != nil {
+			if err := dissolveUndersizedSessionGroup(tx, *sessionExercise.GroupID); err != nil {
+				return err
+			}
+		}
+
+		if err := NewPersonalRecordService(tx).RecalculateRecords(userID, sessionExercise.ExerciseID); err != nil {
+			return err
+		}
+		return refreshWorkoutSummary(tx, userID, workoutID)
+	})
+}
+
+// dissolveUndersizedSessionGroup removes a group once it has too few exercises left
+// for its type, leaving the remaining exercises ungrouped
+func dissolveUndersizedSessionGroup(tx *gorm.DB, groupID uint) error {
+	var group models.SessionExerciseGroup
+	err := tx.First(&group, groupID).Error
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	err = tx.Model(&models.SessionExercise{}).Where("group_id = ?", groupID).Count(&count).Error
+	if err != nil || int(count) >= models.GroupMinExercises(group.GroupType) {
+		return err
+	}
+
+	err = tx.Model(&models.SessionExercise{}).Where("group_id = ?", groupID).Update("group_id", nil).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(&group).Error
+}
+
+// CreateWorkoutGroup groups exercises of a workout (by session exercise ID) into a
+// superset, giant set or circuit
+func (ws *WorkoutService) CreateWorkoutGroup(userID, workoutID uint, groupType, name string, rounds, restBetweenExercises, restAfterRound int, sessionExerciseIDs []uint) (*models.SessionExerciseGroup, error) {
+	// Verify workout ownership
+	var workout models.WorkoutSession
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).First(&workout).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if rounds == 0 {
+		rounds = 1
+	}
+	sessionExerciseIDs = uniqueIDs(sessionExerciseIDs)
+	if err := validateGroup(groupType, len(sessionExerciseIDs), rounds, restBetweenExercises, restAfterRound); err != nil {
+		return nil, err
+	}
+
+	var members []models.SessionExercise
+	err = ws.db.Where("session_id = ? AND id IN ?", workoutID, sessionExerciseIDs).Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(members) != len(sessionExerciseIDs) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	for _, member := range members {
+		if member.GroupID != nil {
+			return nil, fmt.Errorf("%w: session exercise %d is already in a group", ErrInvalidGroup, member.ID)
+		}
+	}
+
+	group := models.SessionExerciseGroup{
+		SessionID:                   workoutID,
+		GroupType:                   groupType,
+		Name:                        strings.TrimSpace(name),
+		Rounds:                      rounds,
+		RestBetweenExercisesSeconds: restBetweenExercises,
+		RestAfterRoundSeconds:       restAfterRound,
+	}
+
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Exercises").Create(&group).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SessionExercise{}).Where("id IN ?", sessionExerciseIDs).Update("group_id", group.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = ws.db.Preload("Exercises.Exercise").
+		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		First(&group, group.ID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// DeleteWorkoutGroup removes a group; its exercises stay in the workout ungrouped
+func (ws *WorkoutService) DeleteWorkoutGroup(userID, workoutID, groupID uint) error {
+	// Verify workout ownership
+	var workout models.WorkoutSession
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).First(&workout).Error
+	if err != nil {
+		return err
+	}
+
+	var group models.SessionExerciseGroup
+	err = ws.db.Where("id = ? AND session_id = ?", groupID, workoutID).First(&group).Error
+	if err != nil {
+		return err
+	}
+
+	return ws.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.SessionExercise{}).Where("group_id = ?", group.ID).Update("group_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&group).Error
+	})
 }
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"onefit/backend/models"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Calories are estimated as MET × body weight in kg × active hours, using the MET of
+// general resistance training
+const (
+	resistanceTrainingMET = 5.0
+	defaultBodyweightKg   = 70.0 // when the user hasn't set their weight
+)
+
+// GetWorkoutSummary returns the summary of a finished workout with the records set
+// during it. Workouts finished before summaries existed get one computed on request.
+func (ws *WorkoutService) GetWorkoutSummary(userID, workoutID uint) (*models.WorkoutSummary, error) {
+	var workout models.WorkoutSession
+	err := ws.db.Where("id = ? AND user_id = ?", workoutID, userID).
+		Preload("Summary.MuscleGroups", orderByVolume).
+		First(&workout).Error
+	if err != nil {
+		return nil, err
+	}
+	if workout.EndedAt == nil {
+		return nil, ErrWorkoutNotEnded
+	}
+
+	if workout.Summary != nil {
+		if err := loadSessionRecords(ws.db, workout.Summary); err != nil {
+			return nil, err
+		}
+		return workout.Summary, nil
+	}
+
+	var summary *models.WorkoutSummary
+	err = ws.db.Transaction(func(tx *gorm.DB) error {
+		summary, err = saveWorkoutSummary(tx, userID, workoutID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// loadSessionRecords fills in the records set during a summary's workout that still
+// stand and beat an earlier best, the same records its sets are flagged with
+func loadSessionRecords(db *gorm.DB, summary *models.WorkoutSummary) error {
+	return db.Where("session_id = ? AND is_current = ? AND previous_value IS NOT NULL", summary.SessionID, true).
+		Preload("Exercise").
+		Order("achieved_at ASC, id ASC").
+		Find(&summary.PersonalRecords).Error
+}
+
+// orderByVolume lists a summary's muscle groups from most to least work
+func orderByVolume(db *gorm.DB) *gorm.DB {
+	return db.Order("volume DESC, sets DESC, muscle_group ASC")
+}
+
+// saveWorkoutSummary computes and stores the summary of a finished workout,
+// replacing an earlier one
+func saveWorkoutSummary(tx *gorm.DB, userID, workoutID uint) (*models.WorkoutSummary, error) {
+	workout, err := NewWorkoutService(tx).GetWorkoutWithDetails(userID, workoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	summary := summarizeWorkout(workout, user.Weight)
+
+	if err := loadSessionRecords(tx, &summary); err != nil {
+		return nil, err
+	}
+	summary.PersonalRecordCount = len(summary.PersonalRecords)
+
+	if err := compareWithPreviousSession(tx, workout, &summary, user.Weight); err != nil {
+		return nil, err
+	}
+
+	// Update in place so the session keeps a single summary row
+	var existing []models.WorkoutSummary
+	if err := tx.Where("session_id = ?", workoutID).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		summary.ID = existing[0].ID
+		summary.CreatedAt = existing[0].CreatedAt
+		err = tx.Unscoped().Where("summary_id = ?", summary.ID).Delete(&models.WorkoutSummaryMuscleGroup{}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Save(&summary).Error; err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// refreshWorkoutSummary recomputes the summary after a finished workout's sets
+// change; ongoing workouts are summarized when they finish. The next workout of the
+// same template compares its totals with this one, so it is refreshed too.
+func refreshWorkoutSummary(tx *gorm.DB, userID, workoutID uint) error {
+	var workout models.WorkoutSession
+	if err := tx.Select("id", "template_id", "started_at", "ended_at").First(&workout, workoutID).Error; err != nil {
+		return err
+	}
+	if workout.EndedAt == nil {
+		return nil
+	}
+
+	if _, err := saveWorkoutSummary(tx, userID, workoutID); err != nil {
+		return err
+	}
+	return refreshNextTemplateSummary(tx, userID, workout)
+}
+
+// refreshNextTemplateSummary recomputes the summary of the user's first finished
+// workout of the same template after the given one, which is compared with it
+func refreshNextTemplateSummary(tx *gorm.DB, userID uint, workout models.WorkoutSession) error {
+	if workout.TemplateID == nil {
+		return nil
+	}
+
+	var nextSessions []models.WorkoutSession
+	err := tx.Where("user_id = ? AND template_id = ? AND id <> ? AND ended_at IS NOT NULL AND started_at > ?",
+		userID, *workout.TemplateID, workout.ID, workout.StartedAt).
+		Order("started_at ASC").
+		Limit(1).
+		Find(&nextSessions).Error
+	if err != nil || len(nextSessions) == 0 {
+		return err
+	}
+
+	_, err = saveWorkoutSummary(tx, userID, nextSessions[0].ID)
+	return err
+}
+
+// refreshRecordCounts recomputes the summaries of the user's workouts with an
+// exercise whose stored record count no longer matches their records, after the
+// exercise's records were rebuilt
+func refreshRecordCounts(tx *gorm.DB, userID, exerciseID uint) error {
+	var staleIDs []uint
+	err := tx.Model(&models.WorkoutSummary{}).
+		Joins("JOIN workout_sessions ON workout_sessions.id = workout_summaries.session_id AND workout_sessions.deleted_at IS NULL").
+		Where("workout_sessions.user_id = ?", userID).
+		Where("workout_summaries.session_id IN (SELECT session_id FROM session_exercises WHERE exercise_id = ? AND deleted_at IS NULL)", exerciseID).
+		Where("workout_summaries.personal_record_count <> (SELECT COUNT(*) FROM personal_records WHERE personal_records.session_id = workout_summaries.session_id AND personal_records.is_current = ? AND personal_records.previous_value IS NOT NULL AND personal_records.deleted_at IS NULL)", true).
+		Pluck("workout_summaries.session_id", &staleIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range staleIDs {
+		if _, err := saveWorkoutSummary(tx, userID, sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarizeWorkout totals a workout's sets by workout and by muscle group. The
+// workout must be loaded with its exercises and sets and have its timing applied.
+func summarizeWorkout(workout *models.WorkoutSession, bodyweight float64) models.WorkoutSummary {
+	summary := models.WorkoutSummary{
+		SessionID:     workout.ID,
+		ActiveSeconds: derefInt(workout.ActiveSeconds),
+		MuscleGroups:  []models.WorkoutSummaryMuscleGroup{},
+	}
+
+	muscleGroups := make(map[string]*models.WorkoutSummaryMuscleGroup)
+	for _, sessionExercise := range workout.Exercises {
+		if len(sessionExercise.Sets) > 0 {
+			summary.ExerciseCount++
+		}
+
+		for _, set := range sessionExercise.Sets {
+			if set.IsWarmup() {
+				summary.WarmupSets++
+				continue
+			}
+
+			volume := setVolume(set)
+			summary.TotalSets++
+			summary.TotalReps += derefInt(set.Reps)
+			summary.TotalVolume += volume
+
+			for _, name := range splitMuscleGroups(sessionExercise.Exercise.MuscleGroups) {
+				muscleGroup, ok := muscleGroups[name]
+				if !ok {
+					muscleGroup = &models.WorkoutSummaryMuscleGroup{MuscleGroup: name}
+					muscleGroups[name] = muscleGroup
+				}
+				muscleGroup.Sets++
+				muscleGroup.Volume += volume
+			}
+		}
+	}
+
+	summary.MuscleGroups = sortedMuscleGroups(muscleGroups)
+
+	summary.TotalVolume = roundTo(summary.TotalVolume, 2)
+
+	if bodyweight <= 0 {
+		bodyweight = defaultBodyweightKg
+	}
+	summary.EstimatedCalories = roundTo(resistanceTrainingMET*bodyweight*float64(summary.ActiveSeconds)/3600, 0)
+
+	return summary
+}
+
+// splitMuscleGroups parses an exercise's comma-separated muscle groups
+func splitMuscleGroups(muscleGroups string) []string {
+	var names []string
+	for _, name := range strings.Split(muscleGroups, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sortedMuscleGroups rounds the volumes and lists the muscle groups from most to
+// least work, the same order as orderByVolume
+func sortedMuscleGroups(byName map[string]*models.WorkoutSummaryMuscleGroup) []models.WorkoutSummaryMuscleGroup {
+	muscleGroups := make([]models.WorkoutSummaryMuscleGroup, 0, len(byName))
+	for _, muscleGroup := range byName {
+		muscleGroup.Volume = roundTo(muscleGroup.Volume, 2)
+		muscleGroups = append(muscleGroups, *muscleGroup)
+	}
+
+	sort.Slice(muscleGroups, func(i, j int) bool {
+		a, b := muscleGroups[i], muscleGroups[j]
+		if a.Volume != b.Volume {
+			return a.Volume > b.Volume
+		}
+		if a.Sets != b.Sets {
+			return a.Sets > b.Sets
+		}
+		return a.MuscleGroup < b.MuscleGroup
+	})
+	return muscleGroups
+}
+
+// compareWithPreviousSession fills in the change since the user's previous finished
+// session of the same template. Freestyle workouts have nothing to compare with.
+func compareWithPreviousSession(tx *gorm.DB, workout *models.WorkoutSession, summary *models.WorkoutSummary, bodyweight float64) error {
+	if workout.TemplateID == nil {
+		return nil
+	}
+
+	var previousSessions []models.WorkoutSession
+	err := tx.Where("user_id = ? AND template_id = ? AND id <> ? AND ended_at IS NOT NULL AND started_at < ?",
+		workout.UserID, *workout.TemplateID, workout.ID, workout.StartedAt).
+		Order("started_at DESC").
+		Limit(1).
+		Preload("Summary").
+		Find(&previousSessions).Error
+	if err != nil || len(previousSessions) == 0 {
+		return err
+	}
+
+	previous := previousSessions[0].Summary
+	if previous == nil {
+		details, err := NewWorkoutService(tx).GetWorkoutWithDetails(workout.UserID, previousSessions[0].ID)
+		if err != nil {
+			return err
+		}
+		previousSummary := summarizeWorkout(details, bodyweight)
+		previous = &previousSummary
+	}
+
+	volumeChange := roundTo(summary.TotalVolume-previous.TotalVolume, 2)
+	setsChange := summary.TotalSets - previous.TotalSets
+	repsChange := summary.TotalReps - previous.TotalReps
+	activeSecondsChange := summary.ActiveSeconds - previous.ActiveSeconds
+
+	summary.PreviousSessionID = &previousSessions[0].ID
+	summary.VolumeChange = &volumeChange
+	summary.SetsChange = &setsChange
+	summary.RepsChange = &repsChange
+	summary.ActiveSecondsChange = &activeSecondsChange
+	if previous.TotalVolume > 0 {
+		volumeChangePercent := roundTo(volumeChange/previous.TotalVolume*100, 1)
+		summary.VolumeChangePercent = &volumeChangePercent
+	}
+
+	return nil
+}
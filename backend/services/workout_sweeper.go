@@ -0,0 +1,183 @@
+package services
+
+import (
+	"log"
+	"onefit/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CloseIdleWorkouts finishes every active workout with no activity for longer than
+// idleTimeout. Starting the workout, logging a set, pausing and resuming all count
+// as activity. The workout ends when its last set was completed, or when it started
+// if nothing was logged, and is flagged as auto-closed. Returns how many were closed.
+func (ws *WorkoutService) CloseIdleWorkouts(idleTimeout time.Duration, now time.Time) (int, error) {
+	var workouts []models.WorkoutSession
+	err := ws.db.Where("ended_at IS NULL AND started_at < ?", now.Add(-idleTimeout)).
+		Preload("Pauses").
+		Find(&workouts).Error
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for i := range workouts {
+		workout := &workouts[i]
+
+		lastSet, err := ws.lastLoggedSet(workout.ID)
+		if err != nil {
+			return closed, err
+		}
+
+		endedAt := workout.StartedAt
+		if lastSet != nil {
+			endedAt = lastSet.CompletedAt
+		}
+
+		lastActivity := endedAt
+		for _, pause := range workout.Pauses {
+			if pause.PausedAt.After(lastActivity) {
+				lastActivity = pause.PausedAt
+			}
+			if pause.ResumedAt != nil && pause.ResumedAt.After(lastActivity) {
+				lastActivity = *pause.ResumedAt
+			}
+		}
+		if now.Sub(lastActivity) <= idleTimeout {
+			continue
+		}
+
+		autoClosed, err := ws.autoCloseWorkout(workout, endedAt)
+		if err != nil {
+			return closed, err
+		}
+		if autoClosed {
+			closed++
+		}
+	}
+
+	return closed, nil
+}
+
+// CloseDuplicateActiveWorkouts auto-closes all but the newest active workout of each
+// user, which were possible before only one could be active. They are closed the
+// same way as idle workouts. Returns how many were closed.
+func (ws *WorkoutService) CloseDuplicateActiveWorkouts() (int, error) {
+	var userIDs []uint
+	err := ws.db.Model(&models.WorkoutSession{}).
+		Where("ended_at IS NULL").
+		Group("user_id").
+		Having("COUNT(*) > 1").
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, userID := range userIDs {
+		var workouts []models.WorkoutSession
+		err := ws.db.Where("user_id = ? AND ended_at IS NULL", userID).
+			Order("started_at DESC, id DESC").
+			Preload("Pauses").
+			Find(&workouts).Error
+		if err != nil {
+			return closed, err
+		}
+
+		for i := range workouts[1:] {
+			workout := &workouts[i+1]
+
+			lastSet, err := ws.lastLoggedSet(workout.ID)
+			if err != nil {
+				return closed, err
+			}
+
+			endedAt := workout.StartedAt
+			if lastSet != nil {
+				endedAt = lastSet.CompletedAt
+			}
+
+			autoClosed, err := ws.autoCloseWorkout(workout, endedAt)
+			if err != nil {
+				return closed, err
+			}
+			if autoClosed {
+				closed++
+			}
+		}
+	}
+
+	return closed, nil
+}
+
+// autoCloseWorkout finishes a workout at endedAt, flags it as auto-closed and saves
+// its summary. Returns false if it was finished or deleted since it was loaded.
+func (ws *WorkoutService) autoCloseWorkout(workout *models.WorkoutSession, endedAt time.Time) (bool, error) {
+	openPause := finishWorkout(workout, endedAt)
+
+	autoClosed := false
+	err := ws.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.WorkoutSession{}).
+			Where("id = ? AND ended_at IS NULL", workout.ID).
+			Updates(map[string]interface{}{
+				"ended_at":         workout.EndedAt,
+				"duration_minutes": workout.DurationMinutes,
+				"elapsed_seconds":  workout.ElapsedSeconds,
+				"active_seconds":   workout.ActiveSeconds,
+				"auto_closed":      true,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if openPause != nil {
+			if err := tx.Save(openPause).Error; err != nil {
+				return err
+			}
+		}
+
+		autoClosed = true
+		_, err := saveWorkoutSummary(tx, workout.UserID, workout.ID)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return autoClosed, nil
+}
+
+// lastLoggedSet returns the most recently completed set of a workout, or nil
+func (ws *WorkoutService) lastLoggedSet(sessionID uint) (*models.ExerciseSet, error) {
+	var sets []models.ExerciseSet
+	err := ws.db.Joins("JOIN session_exercises ON session_exercises.id = exercise_sets.session_exercise_id").
+		Where("session_exercises.session_id = ? AND session_exercises.deleted_at IS NULL", sessionID).
+		Order("exercise_sets.completed_at DESC").
+		Limit(1).
+		Find(&sets).Error
+	if err != nil || len(sets) == 0 {
+		return nil, err
+	}
+	return &sets[0], nil
+}
+
+// StartIdleWorkoutSweeper closes idle workouts in the background, checking every
+// interval until the process exits
+func StartIdleWorkoutSweeper(db *gorm.DB, idleTimeout, interval time.Duration) {
+	ws := NewWorkoutService(db)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			closed, err := ws.CloseIdleWorkouts(idleTimeout, time.Now())
+			if err != nil {
+				log.Printf("Warning: Failed to close idle workouts: %v", err)
+			} else if closed > 0 {
+				log.Printf("Auto-closed %d idle workouts", closed)
+			}
+
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"onefit/backend/models"
+	"time"
+)
+
+// pausedBetween returns how much of the interval from..to the workout spent paused.
+// An ongoing pause counts until to.
+func pausedBetween(pauses []models.WorkoutPause, from, to time.Time) time.Duration {
+	var paused time.Duration
+	for _, pause := range pauses {
+		start := pause.PausedAt
+		if start.Before(from) {
+			start = from
+		}
+
+		end := to
+		if pause.ResumedAt != nil && pause.ResumedAt.Before(to) {
+			end = *pause.ResumedAt
+		}
+
+		if end.After(start) {
+			paused += end.Sub(start)
+		}
+	}
+	return paused
+}
+
+// applyWorkoutTiming fills in the elapsed and active seconds of a workout and
+// whether it is paused. Finished workouts are measured to their end, ongoing ones
+// to now.
+func applyWorkoutTiming(workout *models.WorkoutSession, now time.Time) {
+	end := now
+	if workout.EndedAt != nil {
+		end = *workout.EndedAt
+	}
+
+	elapsed := max(int(end.Sub(workout.StartedAt).Seconds()), 0)
+	paused := int(pausedBetween(workout.Pauses, workout.StartedAt, end).Seconds())
+	active := max(elapsed-paused, 0)
+
+	workout.ElapsedSeconds = &elapsed
+	workout.ActiveSeconds = &active
+
+	workout.IsPaused = false
+	if workout.EndedAt == nil {
+		for _, pause := range workout.Pauses {
+			if pause.ResumedAt == nil {
+				workout.IsPaused = true
+			}
+		}
+	}
+}
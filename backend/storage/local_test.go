@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStorage(t *testing.T, signingKey string) *LocalStorage {
+	t.Helper()
+	store, err := NewLocalStorage(t.TempDir(), "http://localhost:3000/", []byte(signingKey))
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return store
+}
+
+// parseSignedURL splits a /media URL into its key, expiry and signature
+func parseSignedURL(t *testing.T, signedURL string) (string, string, string) {
+	t.Helper()
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", signedURL, err)
+	}
+	if u.Host != "localhost:3000" || !strings.HasPrefix(u.Path, "/media/") {
+		t.Fatalf("unexpected signed URL %q", signedURL)
+	}
+	return strings.TrimPrefix(u.Path, "/media/"), u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestLocalStoragePutGetDelete(t *testing.T) {
+	store := newTestLocalStorage(t, "secret")
+	ctx := context.Background()
+	key := "exercises/1/images/photo.jpg"
+
+	if err := store.Put(ctx, key, strings.NewReader("image data"), 10, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(data, []byte("image data")) {
+		t.Fatalf("Get returned %q", data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Get after delete: got %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	store := newTestLocalStorage(t, "secret")
+
+	for _, key := range []string{"", "../outside.jpg", "exercises/../../outside.jpg", "/absolute.jpg", "exercises//double.jpg"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+		if _, err := store.SignedURL(key, time.Minute); err == nil {
+			t.Errorf("SignedURL(%q) succeeded, want an error", key)
+		}
+	}
+}
+
+func TestLocalStorageSignedURL(t *testing.T) {
+	store := newTestLocalStorage(t, "secret")
+
+	signedURL, err := store.SignedURL("exercises/1/images/photo.jpg", time.Hour)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	key, expires, signature := parseSignedURL(t, signedURL)
+
+	if !store.VerifySignature(key, expires, signature) {
+		t.Fatal("a freshly signed URL was rejected")
+	}
+
+	tests := []struct {
+		name      string
+		store     *LocalStorage
+		key       string
+		expires   string
+		signature string
+	}{
+		{"other key", store, "exercises/1/images/other.jpg", expires, signature},
+		{"extended expiry", store, key, expires + "0", signature},
+		{"malformed expiry", store, key, "soon", signature},
+		{"tampered signature", store, key, expires, strings.Repeat("0", len(signature))},
+		{"missing signature", store, key, expires, ""},
+		{"other signing key", newTestLocalStorage(t, "another secret"), key, expires, signature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.store.VerifySignature(tt.key, tt.expires, tt.signature) {
+				t.Fatal("VerifySignature accepted a URL it should reject")
+			}
+		})
+	}
+}
+
+func TestLocalStorageExpiredURL(t *testing.T) {
+	store := newTestLocalStorage(t, "secret")
+
+	signedURL, err := store.SignedURL("exercises/1/images/photo.jpg", -time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	key, expires, signature := parseSignedURL(t, signedURL)
+
+	if store.VerifySignature(key, expires, signature) {
+		t.Fatal("an expired URL was accepted")
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Register decoders for the upload formats we can thumbnail
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// maxThumbnailSourcePixels guards against small files that decode into huge images
+const maxThumbnailSourcePixels = 50_000_000
+
+// GenerateThumbnail decodes a JPEG, PNG, GIF or WebP image and returns a JPEG scaled so
+// that neither side exceeds maxSize. Images already within bounds are re-encoded as-is.
+func GenerateThumbnail(data []byte, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("thumbnail size must be positive")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding image: %v", err)
+	}
+	if config.Width*config.Height > maxThumbnailSourcePixels {
+		return nil, fmt.Errorf("image is too large to thumbnail (%dx%d)", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding image: %v", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("image has no pixels")
+	}
+
+	// Keep the aspect ratio, scaling the longest side down to maxSize
+	thumbWidth, thumbHeight := width, height
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			thumbWidth = maxSize
+			thumbHeight = max(1, height*maxSize/width)
+		} else {
+			thumbHeight = maxSize
+			thumbWidth = max(1, width*maxSize/height)
+		}
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+
+	// Box filter: every thumbnail pixel averages the source pixels it covers
+	for ty := 0; ty < thumbHeight; ty++ {
+		y0 := bounds.Min.Y + ty*height/thumbHeight
+		y1 := max(y0+1, bounds.Min.Y+(ty+1)*height/thumbHeight)
+
+		for tx := 0; tx < thumbWidth; tx++ {
+			x0 := bounds.Min.X + tx*width/thumbWidth
+			x1 := max(x0+1, bounds.Min.X+(tx+1)*width/thumbWidth)
+
+			var r, g, b, a, count uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					pr, pg, pb, pa := src.At(x, y).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					count++
+				}
+			}
+
+			// Flatten transparency onto white since JPEG has no alpha channel
+			background := count*0xffff - a
+			thumb.Set(tx, ty, color.RGBA64{
+				R: uint16((r + background) / count),
+				G: uint16((g + background) / count),
+				B: uint16((b + background) / count),
+				A: 0xffff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("error encoding thumbnail: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}